@@ -0,0 +1,204 @@
+package clickhouse
+
+import (
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Default clause sets wired into callbacks.Config by Initialize. Assign to
+// these before calling Open/New (or copy them into your own slice and pass
+// it via a custom gorm.Dialector wrapper) to inject extra clauses without
+// monkey-patching the callback - the same pattern GORM's own MySQL and
+// DameEng dialectors use.
+var (
+	CreateClauses = []string{"INSERT", "VALUES", "ON CONFLICT"}
+	QueryClauses  = []string{"SELECT", "FROM", "FINAL", "SAMPLE", "PREWHERE", "WHERE", "GROUP BY", "ORDER BY", "LIMIT", "FOR", "SETTINGS"}
+	UpdateClauses = []string{"UPDATE", "SET", "WHERE", "SETTINGS"}
+	DeleteClauses = []string{"DELETE", "WHERE", "SETTINGS"}
+)
+
+// mutationClauseName stashes a Mutation's OnCluster in stmt.Clauses under a
+// key that never appears in DeleteClauses/UpdateClauses, so it rides along
+// on the Statement without being rendered by Statement.Build's ordinary
+// clause loop; the DELETE/UPDATE ClauseBuilders below read it directly.
+const mutationClauseName = "clickhouse:mutation"
+
+// mutationExpr renders a Mutation's ON CLUSTER fragment on demand; see
+// mutationClauseName.
+type mutationExpr struct {
+	onCluster string
+}
+
+func (m mutationExpr) Build(builder clause.Builder) {
+	if m.onCluster != "" {
+		builder.WriteString(" ON CLUSTER ")
+		builder.WriteString(m.onCluster)
+	}
+}
+
+// Mutation configures the ALTER TABLE that the DELETE and UPDATE
+// ClauseBuilders rewrite their statement into:
+//
+//	db.Clauses(clickhouse.Mutation{OnCluster: "c1", Sync: true}).
+//		Where("id = ?", id).Delete(&User{})
+//
+// produces `ALTER TABLE db.t ON CLUSTER c1 DELETE WHERE id = ? SETTINGS
+// mutations_sync = ?`, with 2 bound as mutations_sync's value. Sync makes the ALTER block until every replica has
+// applied the mutation instead of returning as soon as it's queued.
+type Mutation struct {
+	OnCluster string
+	Sync      bool
+}
+
+// Build implements clause.Expression so Mutation satisfies the parameter
+// type db.Clauses takes; the actual work happens in ModifyStatement, which
+// gorm calls instead once it sees Mutation also implements StatementModifier.
+func (m Mutation) Build(clause.Builder) {}
+
+// ModifyStatement implements gorm.StatementModifier.
+func (m Mutation) ModifyStatement(stmt *gorm.Statement) {
+	stmt.Clauses[mutationClauseName] = clause.Clause{
+		Name:       mutationClauseName,
+		Expression: mutationExpr{onCluster: m.OnCluster},
+	}
+	if m.Sync {
+		stmt.AddClause(Settings(Setting{Key: "mutations_sync", Value: 2}))
+	}
+}
+
+// writeMutationOnCluster writes the effective ON CLUSTER fragment right after
+// the table name in the ALTER TABLE the DELETE/UPDATE ClauseBuilders emit: an
+// explicit db.Clauses(clickhouse.Mutation{OnCluster: ...}) wins, otherwise it
+// falls back to defaultCluster (the dialector's own Config.Cluster).
+func writeMutationOnCluster(builder clause.Builder, defaultCluster string) {
+	cluster := defaultCluster
+	if stmt, ok := builder.(*gorm.Statement); ok {
+		if c, ok := stmt.Clauses[mutationClauseName]; ok {
+			if m, ok := c.Expression.(mutationExpr); ok && m.onCluster != "" {
+				cluster = m.onCluster
+			}
+		}
+	}
+	if cluster != "" {
+		builder.WriteString(" ON CLUSTER ")
+		builder.WriteString(cluster)
+	}
+}
+
+// PrewhereClause renders `PREWHERE <expr>`, letting ClickHouse discard whole
+// granules before it evaluates WHERE or reads the rest of a row's columns.
+// Build one with Prewhere.
+type PrewhereClause struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// Prewhere builds a PREWHERE clause the same way db.Where builds a WHERE
+// one: a SQL fragment with `?` placeholders and its bind vars, e.g.
+//
+//	db.Clauses(clickhouse.Prewhere("event_date = ?", today)).Find(&events)
+func Prewhere(query string, args ...interface{}) PrewhereClause {
+	return PrewhereClause{SQL: query, Vars: args}
+}
+
+func (PrewhereClause) Name() string { return "PREWHERE" }
+
+func (p PrewhereClause) Build(builder clause.Builder) {
+	clause.Expr{SQL: p.SQL, Vars: p.Vars}.Build(builder)
+}
+
+func (p PrewhereClause) MergeClause(c *clause.Clause) {
+	c.Expression = p
+}
+
+// FinalClause renders FINAL right after the FROM table, forcing ClickHouse
+// to apply ReplacingMergeTree/CollapsingMergeTree's row collapsing at query
+// time instead of returning whatever the background merge has produced so
+// far. Build one with Final.
+type FinalClause struct{}
+
+// Final builds a FINAL clause, e.g. db.Clauses(clickhouse.Final()).Find(&users).
+func Final() FinalClause { return FinalClause{} }
+
+func (FinalClause) Name() string { return "FINAL" }
+
+func (FinalClause) Build(builder clause.Builder) {
+	builder.WriteString("FINAL")
+}
+
+func (f FinalClause) MergeClause(c *clause.Clause) {
+	c.Name = ""
+	c.Expression = f
+}
+
+// SampleClause renders a SAMPLE clause. Build one with Sample (a sampling
+// coefficient between 0 and 1, or a row count) or SampleOffset (a
+// coefficient plus an OFFSET, for paging through consecutive samples).
+type SampleClause struct {
+	expr string
+}
+
+// Sample builds a `SAMPLE k` clause, e.g. clickhouse.Sample(0.1) for a 10%
+// sample or clickhouse.Sample(1_000_000) to sample (at least) that many rows.
+func Sample(k float64) SampleClause {
+	return SampleClause{expr: strconv.FormatFloat(k, 'f', -1, 64)}
+}
+
+// SampleOffset builds a `SAMPLE n OFFSET d` clause for reading consecutive,
+// non-overlapping samples of a table across repeated queries.
+func SampleOffset(n, d float64) SampleClause {
+	return SampleClause{expr: strconv.FormatFloat(n, 'f', -1, 64) + " OFFSET " + strconv.FormatFloat(d, 'f', -1, 64)}
+}
+
+func (SampleClause) Name() string { return "SAMPLE" }
+
+func (s SampleClause) Build(builder clause.Builder) {
+	builder.WriteString(s.expr)
+}
+
+func (s SampleClause) MergeClause(c *clause.Clause) {
+	c.Expression = s
+}
+
+// SettingsClause renders a trailing `SETTINGS key = value, ...` clause in
+// the order given - ClickHouse settings aren't order-sensitive, but
+// generated SQL should still be deterministic. Build one with Settings; it
+// reuses the Setting type TableOptions.Settings already uses for CREATE
+// TABLE's SETTINGS clause.
+type SettingsClause []Setting
+
+// Settings builds a SETTINGS clause, e.g.
+//
+//	db.Clauses(clickhouse.Settings(
+//		clickhouse.Setting{Key: "max_threads", Value: 8},
+//		clickhouse.Setting{Key: "join_algorithm", Value: "parallel_hash"},
+//	)).Find(&rows)
+func Settings(settings ...Setting) SettingsClause {
+	return SettingsClause(settings)
+}
+
+func (SettingsClause) Name() string { return "SETTINGS" }
+
+func (s SettingsClause) Build(builder clause.Builder) {
+	for i, setting := range s {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(setting.Key)
+		builder.WriteString(" = ")
+		builder.AddVar(builder, setting.Value)
+	}
+}
+
+func (s SettingsClause) MergeClause(c *clause.Clause) {
+	// A later db.Clauses(clickhouse.Settings(...)) call - or the implicit
+	// one Mutation{Sync: true} adds - appends to rather than replaces earlier
+	// settings, so e.g. mutations_sync doesn't clobber a caller's own block.
+	if existing, ok := c.Expression.(SettingsClause); ok {
+		c.Expression = append(append(SettingsClause{}, existing...), s...)
+		return
+	}
+	c.Expression = s
+}
@@ -0,0 +1,112 @@
+package clickhouse
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// newDryRunDB returns a gorm.DB configured to render SQL without executing
+// it or contacting a server, so clause builders and Migrator SQL assembly
+// can be exercised without a live ClickHouse connection.
+func newDryRunDB(t *testing.T, cfg Config) *gorm.DB {
+	t.Helper()
+	cfg.SkipInitializeWithVersion = true
+	if cfg.DriverName == "" {
+		cfg.DriverName = "clickhouse"
+	}
+	db, err := gorm.Open(New(cfg), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	return db
+}
+
+func TestQueryClauseBuilders(t *testing.T) {
+	db := newDryRunDB(t, Config{})
+
+	stmt := db.Clauses(
+		Final(),
+		Prewhere("event_type = ?", "click"),
+		Sample(0.1),
+		Settings(Setting{Key: "max_threads", Value: 8}),
+	).Find(&[]batchTestRow{}).Statement
+
+	sql := stmt.SQL.String()
+	for _, want := range []string{"FINAL", "PREWHERE", "SAMPLE 0.1", "SETTINGS max_threads"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL = %q, want it to contain %q", sql, want)
+		}
+	}
+}
+
+func TestSampleOffset(t *testing.T) {
+	db := newDryRunDB(t, Config{})
+
+	stmt := db.Clauses(SampleOffset(0.1, 0.2)).Find(&[]batchTestRow{}).Statement
+	if got, want := stmt.SQL.String(), "SAMPLE 0.1 OFFSET 0.2"; !strings.Contains(got, want) {
+		t.Errorf("SQL = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestSettingsClauseAccumulates(t *testing.T) {
+	db := newDryRunDB(t, Config{})
+
+	stmt := db.Clauses(
+		Settings(Setting{Key: "max_threads", Value: 8}),
+		Settings(Setting{Key: "join_algorithm", Value: "parallel_hash"}),
+	).Find(&[]batchTestRow{}).Statement
+
+	if got, want := stmt.SQL.String(), "SETTINGS max_threads = ?, join_algorithm = ?"; !strings.Contains(got, want) {
+		t.Errorf("SQL = %q, want it to contain %q", got, want)
+	}
+	if len(stmt.Vars) < 2 || stmt.Vars[len(stmt.Vars)-2] != 8 || stmt.Vars[len(stmt.Vars)-1] != "parallel_hash" {
+		t.Errorf("Vars = %v, want the last two to be 8 and \"parallel_hash\"", stmt.Vars)
+	}
+}
+
+func TestMutationOnClusterFallsBackToConfigCluster(t *testing.T) {
+	db := newDryRunDB(t, Config{Cluster: "c1"})
+
+	stmt := db.Where("id = ?", 1).Delete(&batchTestRow{}).Statement
+	got := stmt.SQL.String()
+	if !strings.Contains(got, "ALTER TABLE") || !strings.Contains(got, "ON CLUSTER c1") || !strings.Contains(got, "DELETE") {
+		t.Errorf("Delete SQL = %q, want an ALTER TABLE ... ON CLUSTER c1 ... DELETE", got)
+	}
+}
+
+func TestMutationExplicitOnClusterWinsOverConfig(t *testing.T) {
+	db := newDryRunDB(t, Config{Cluster: "c1"})
+
+	stmt := db.Clauses(Mutation{OnCluster: "c2"}).Where("id = ?", 1).Delete(&batchTestRow{}).Statement
+	got := stmt.SQL.String()
+	if !strings.Contains(got, "ON CLUSTER c2") || strings.Contains(got, "ON CLUSTER c1") {
+		t.Errorf("Delete SQL = %q, want the explicit Mutation.OnCluster (c2) to win over Config.Cluster (c1)", got)
+	}
+}
+
+func TestMutationSyncAddsMutationsSyncSetting(t *testing.T) {
+	db := newDryRunDB(t, Config{})
+
+	stmt := db.Clauses(Mutation{Sync: true}).Where("id = ?", 1).Delete(&batchTestRow{}).Statement
+	if got, want := stmt.SQL.String(), "SETTINGS mutations_sync = ?"; !strings.Contains(got, want) {
+		t.Errorf("Delete SQL = %q, want it to contain %q", got, want)
+	}
+	if len(stmt.Vars) == 0 || stmt.Vars[len(stmt.Vars)-1] != 2 {
+		t.Errorf("Vars = %v, want the last one to be mutations_sync's value, 2", stmt.Vars)
+	}
+}
+
+func TestNoOnClusterWhenNeitherConfigNorMutationSetIt(t *testing.T) {
+	db := newDryRunDB(t, Config{})
+
+	stmt := db.Where("id = ?", 1).Delete(&batchTestRow{}).Statement
+	if got := stmt.SQL.String(); strings.Contains(got, "ON CLUSTER") {
+		t.Errorf("Delete SQL = %q, want no ON CLUSTER fragment", got)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/ClickHouse/clickhouse-go"
+	chv2 "github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/hashicorp/go-version"
 	"gorm.io/gorm"
 	"gorm.io/gorm/callbacks"
@@ -33,6 +33,49 @@ type Config struct {
 	PoolMaxOpenCount          int           // <= 0 means unlimited
 	PoolMaxLifetime           time.Duration // maximum amount of time a connection may be reused
 	PoolMaxIdleTime           time.Duration // maximum amount of time a connection may be idle before being closed
+	// Protocol picks the wire protocol used when opening DSN: "native" (the
+	// default) speaks ClickHouse's native TCP protocol via clickhouse-go/v2,
+	// "http" speaks its HTTP protocol. Ignored once Options or Conn is set,
+	// since those already carry their own protocol.
+	Protocol string
+	// Options configures the clickhouse-go/v2 native driver directly - TLS,
+	// compression, per-connection Settings, connection-pool sizing - instead
+	// of a DSN string. When set, Initialize opens the connection with
+	// chv2.OpenDB(Options) and DSN/Protocol are ignored.
+	Options *chv2.Options
+	// AsyncInsert makes every INSERT issued through the Create callback opt
+	// into ClickHouse's async insert path by attaching the query settings
+	// async_insert=1 and wait_for_async_insert=0 to the statement's context
+	// (not to the SQL text), trading immediate durability for throughput on
+	// small, frequent writes.
+	AsyncInsert bool
+	// TypeMapper is consulted by DataTypeOf before anything else, for every
+	// field - including ones GORM already resolved to an ordinary
+	// schema.DataType (where named Go types such as uuid.UUID and net.IP end
+	// up after GORM's own Valuer/[]byte unwrapping, pointers such as *string
+	// end up as their element's DataType, and enum8/enum16/
+	// precision-tag-decimal features live on otherwise plain Bool/Int/Uint
+	// fields) as well as ones whose DataType is a literal `gorm:"type:..."`
+	// tag (such as slices/maps tagged that way to opt out of relation
+	// detection). Declining (ok=false) falls back to DataTypeOf's own
+	// Size/Precision-aware switch, or to the literal `gorm:"type:..."` tag if
+	// that switch doesn't match either. See DefaultTypeMapper for a
+	// ready-made implementation that declines for exactly the fields
+	// DataTypeOf's switch already renders correctly.
+	TypeMapper TypeMapper
+
+	// Cluster, when set, is appended as "ON CLUSTER <Cluster>" to every DDL
+	// statement Migrator issues (CreateTable, DropTable, AddColumn,
+	// AlterColumn, CreateIndex, RenameTable) and, unless overridden per call
+	// via clickhouse.Mutation{OnCluster: ...}, to the ALTER-rewritten
+	// DELETE/UPDATE statements in Dialector.ClauseBuilders.
+	Cluster string
+
+	// version is the ClickHouse server version discovered during
+	// Initialize. Capability gates (DontSupportRenameColumn today; lightweight
+	// deletes on 23.3+, the JSON type on 24.8+ tomorrow) key off it instead of
+	// re-querying `SELECT version()`. See Dialector.ServerVersion.
+	version *version.Version
 }
 
 type Dialector struct {
@@ -51,11 +94,55 @@ func (d Dialector) Name() string {
 	return "clickhouse"
 }
 
+// dsn returns d.DSN, adding an "http://" scheme when d.Protocol is "http"
+// and the DSN doesn't already specify one of its own. Any other DSN is
+// returned unchanged.
+func (d Dialector) dsn() string {
+	if d.Protocol == "http" && !strings.Contains(d.DSN, "://") {
+		return "http://" + d.DSN
+	}
+	return d.DSN
+}
+
+// ServerVersion returns the ClickHouse server version discovered during
+// Initialize, or nil if Initialize hasn't run yet or was configured with
+// SkipInitializeWithVersion.
+func (d Dialector) ServerVersion() *version.Version {
+	return d.Config.version
+}
+
+// SupportsLightweightDelete reports whether the connected server is new
+// enough (23.3+) to run `DELETE FROM ... WHERE ...` as a lightweight delete
+// instead of requiring the heavier ALTER TABLE ... DELETE mutation path.
+func (d Dialector) SupportsLightweightDelete() bool {
+	return d.versionAtLeast("23.3")
+}
+
+// SupportsJSONType reports whether the connected server is new enough
+// (24.8+) to offer the native JSON column type.
+func (d Dialector) SupportsJSONType() bool {
+	return d.versionAtLeast("24.8")
+}
+
+func (d Dialector) versionAtLeast(constraint string) bool {
+	if d.Config.version == nil {
+		return false
+	}
+	c, err := version.NewConstraint(">= " + constraint)
+	if err != nil {
+		return false
+	}
+	return c.Check(d.Config.version)
+}
+
 func (d Dialector) Initialize(db *gorm.DB) (err error) {
 	// register callbacks
 	ctx := context.Background()
 	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{
-		DeleteClauses: []string{"DELETE", "WHERE"},
+		CreateClauses: CreateClauses,
+		QueryClauses:  QueryClauses,
+		UpdateClauses: UpdateClauses,
+		DeleteClauses: DeleteClauses,
 	})
 	db.Callback().Create().Replace("gorm:create", Create)
 
@@ -83,10 +170,12 @@ func (d Dialector) Initialize(db *gorm.DB) (err error) {
 
 	if d.Conn != nil {
 		db.ConnPool = d.Conn
+	} else if d.Options != nil {
+		db.ConnPool = chv2.OpenDB(d.Options)
 	} else {
 		var dbb *sql.DB
 
-		dbb, err = sql.Open(d.DriverName, d.DSN)
+		dbb, err = sql.Open(d.DriverName, d.dsn())
 		if err != nil {
 			return err
 		}
@@ -112,14 +201,14 @@ func (d Dialector) Initialize(db *gorm.DB) (err error) {
 
 	if !d.SkipInitializeWithVersion {
 		var vs string
-		err = db.ConnPool.QueryRowContext(ctx, "SELECT version()").Scan(&vs)
-		if err != nil {
+		if err = db.ConnPool.QueryRowContext(ctx, "SELECT version()").Scan(&vs); err != nil {
 			return err
 		}
-		dbversion, _ := version.NewVersion(vs)
+
+		d.Config.version, _ = version.NewVersion(vs)
 		versionNoRenameColumn, _ := version.NewConstraint("< 20.4")
 
-		if versionNoRenameColumn.Check(dbversion) {
+		if versionNoRenameColumn.Check(d.Config.version) {
 			d.Config.DontSupportRenameColumn = true
 		}
 	}
@@ -182,6 +271,7 @@ func (d Dialector) ClauseBuilders() map[string]clause.ClauseBuilder {
 			if !addedTable {
 				builder.WriteQuoted(clause.Table{Name: clause.CurrentTable})
 			}
+			writeMutationOnCluster(builder, d.Config.Cluster)
 			builder.WriteString(" DELETE")
 		},
 		"UPDATE": func(c clause.Clause, builder clause.Builder) {
@@ -200,6 +290,7 @@ func (d Dialector) ClauseBuilders() map[string]clause.ClauseBuilder {
 			if !addedTable {
 				builder.WriteQuoted(clause.Table{Name: clause.CurrentTable})
 			}
+			writeMutationOnCluster(builder, d.Config.Cluster)
 			builder.WriteString(" UPDATE")
 		},
 		"SET": func(c clause.Clause, builder clause.Builder) {
@@ -224,6 +315,23 @@ func (d Dialector) Migrator(db *gorm.DB) gorm.Migrator {
 }
 
 func (d Dialector) DataTypeOf(field *schema.Field) string {
+	// Consult the TypeMapper before anything else, not just for fields GORM
+	// left with an empty DataType. schema.Parse already resolves some named
+	// Go types (uuid.UUID, net.IP) and tag-driven features (enum8/enum16,
+	// precision-tag decimals) to an ordinary Bool/Int/Uint/String/Bytes/Time
+	// DataType before DataTypeOf ever sees the field, so by the time the
+	// switch below runs, whatever made the field distinctive is already gone
+	// from field.DataType itself - the TypeMapper matches by field.FieldType
+	// and field.TagSettings instead, so it needs first refusal on every
+	// field, not just the ones GORM couldn't classify at all. DefaultTypeMapper
+	// declines (ok=false) for the plain Go kinds this switch already handles,
+	// so the fallback logic below stays authoritative for those.
+	if d.TypeMapper != nil {
+		if sqlType, ok := d.TypeMapper(field); ok {
+			return sqlType
+		}
+	}
+
 	switch field.DataType {
 	case schema.Bool:
 		return "UInt8"
@@ -249,13 +357,11 @@ func (d Dialector) DataTypeOf(field *schema.Field) string {
 			return "Float32"
 		}
 		return "Float64"
-	case schema.String:
-		if field.Size == 0 {
+	case schema.String, schema.Bytes:
+		if field.DataType == schema.Bytes || field.Size == 0 {
 			return "String"
 		}
 		return fmt.Sprintf("FixedString(%d)", field.Size)
-	case schema.Bytes:
-		return "String"
 	case schema.Time:
 		// TODO: support TimeZone
 		precision := ""
@@ -270,6 +376,11 @@ func (d Dialector) DataTypeOf(field *schema.Field) string {
 		return "DateTime64" + precision
 	}
 
+	// field.DataType is only populated from the basic Go kinds above or from a
+	// literal `gorm:"type:..."` tag, so anything that reaches here is such a
+	// tag (e.g. Array(String) on a slice field, which also keeps GORM from
+	// treating it as a relation) or empty. The TypeMapper already had its
+	// chance above, so the tag is returned verbatim.
 	return string(field.DataType)
 }
 
@@ -0,0 +1,106 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// versionDriver is a database/sql/driver fake whose QueryContext always
+// returns a single row containing serverVersion, so Initialize's
+// `SELECT version()` probe can be exercised without a live ClickHouse server.
+type versionDriver struct {
+	serverVersion string
+	queries       []string
+}
+
+func (d *versionDriver) Open(name string) (driver.Conn, error) {
+	return &versionConn{driver: d}, nil
+}
+
+type versionConn struct {
+	driver *versionDriver
+}
+
+func (c *versionConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+func (c *versionConn) Close() error              { return nil }
+func (c *versionConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+func (c *versionConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.queries = append(c.driver.queries, query)
+	return &versionRows{version: c.driver.serverVersion}, nil
+}
+
+type versionRows struct {
+	version string
+	done    bool
+}
+
+func (r *versionRows) Columns() []string { return []string{"version()"} }
+func (r *versionRows) Close() error      { return nil }
+func (r *versionRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.version
+	return nil
+}
+
+func TestInitializeQueriesServerVersion(t *testing.T) {
+	fake := &versionDriver{serverVersion: "23.8.1"}
+	driverName := "clickhouse-version-test-" + t.Name()
+	sql.Register(driverName, fake)
+
+	conn, err := sql.Open(driverName, "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer conn.Close()
+
+	dialector := New(Config{Conn: conn})
+	db, err := gorm.Open(dialector, &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+
+	d := db.Config.Dialector.(*Dialector)
+	if got := d.ServerVersion(); got == nil || got.String() != fake.serverVersion {
+		t.Errorf("ServerVersion() = %v, want %s", got, fake.serverVersion)
+	}
+	if len(fake.queries) != 1 || fake.queries[0] != "SELECT version()" {
+		t.Errorf("queries = %v, want exactly one \"SELECT version()\"", fake.queries)
+	}
+}
+
+func TestInitializeSkipsVersionQuery(t *testing.T) {
+	fake := &versionDriver{serverVersion: "23.8.1"}
+	driverName := "clickhouse-version-test-" + t.Name()
+	sql.Register(driverName, fake)
+
+	conn, err := sql.Open(driverName, "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer conn.Close()
+
+	dialector := New(Config{Conn: conn, SkipInitializeWithVersion: true})
+	db, err := gorm.Open(dialector, &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+
+	d := db.Config.Dialector.(*Dialector)
+	if got := d.ServerVersion(); got != nil {
+		t.Errorf("ServerVersion() = %v, want nil when SkipInitializeWithVersion is set", got)
+	}
+	if len(fake.queries) != 0 {
+		t.Errorf("queries = %v, want none", fake.queries)
+	}
+}
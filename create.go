@@ -0,0 +1,127 @@
+package clickhouse
+
+import (
+	"database/sql"
+
+	chv2 "github.com/ClickHouse/clickhouse-go/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+// insertSyncSessionKey is set via db.Set("clickhouse:insert_sync", true) to
+// opt an insert into insert_distributed_sync, so writes against a Distributed
+// table are visible to the caller immediately instead of only once
+// ClickHouse has finished forwarding them to the shards in the background -
+// useful for tests and small jobs, at the cost of a slower, blocking insert.
+const insertSyncSessionKey = "clickhouse:insert_sync"
+
+// insertSettings builds the native query settings a Create call needs to
+// apply, from Config.AsyncInsert and the per-call insert_distributed_sync
+// opt-in. These are threaded onto the statement's context (see Create)
+// rather than rendered as SQL text, because clickhouse-go/v2's std driver
+// normalizes every prepared INSERT via extractNormalizedInsertQueryAndColumns,
+// which truncates everything from " VALUES " onward - a trailing SETTINGS
+// clause appended to the SQL text would silently vanish before the query
+// ever reaches the server.
+func insertSettings(asyncInsert, insertSync bool) chv2.Settings {
+	settings := chv2.Settings{}
+	if asyncInsert {
+		settings["async_insert"] = 1
+		settings["wait_for_async_insert"] = 0
+	}
+	if insertSync {
+		settings["insert_distributed_sync"] = 1
+	}
+	return settings
+}
+
+// asyncInsertEnabled reports whether dialector is this package's *Dialector
+// with Config.AsyncInsert set. New and Open both return *Dialector (not
+// Dialector), so this must assert against the pointer type to ever succeed
+// for a dialector built through the public API.
+func asyncInsertEnabled(dialector gorm.Dialector) bool {
+	d, ok := dialector.(*Dialector)
+	return ok && d.Config != nil && d.Config.AsyncInsert
+}
+
+func Create(db *gorm.DB) {
+	if db.Error == nil {
+		if db.Statement.Schema != nil && !db.Statement.Unscoped {
+			for _, c := range db.Statement.Schema.CreateClauses {
+				db.Statement.AddClause(c)
+			}
+		}
+
+		asyncInsert := asyncInsertEnabled(db.Config.Dialector)
+		insertSync, _ := db.Get(insertSyncSessionKey)
+		sync, _ := insertSync.(bool)
+
+		if settings := insertSettings(asyncInsert, sync); len(settings) > 0 {
+			db.Statement.Context = chv2.Context(db.Statement.Context, chv2.WithSettings(settings))
+		}
+
+		if db.Statement.SQL.String() == "" {
+			db.Statement.SQL.Grow(180)
+			db.Statement.AddClauseIfNotExists(clause.Insert{})
+
+			if values := callbacks.ConvertToCreateValues(db.Statement); len(values.Values) > 1 {
+				prepareValues := clause.Values{
+					Columns: values.Columns,
+					Values:  [][]interface{}{values.Values[0]},
+				}
+				db.Statement.AddClause(prepareValues)
+				db.Statement.Build(db.Statement.BuildClauses...)
+
+				// clickhouse-go/v2's std driver only turns a prepared INSERT
+				// into one native batch when Commit is called on the *sql.Tx
+				// that prepared it (see its examples/std/batch.go), so the
+				// whole slice is wrapped in its own transaction here. If the
+				// caller already opened one, ConnPool is a *sql.Tx and the
+				// caller's own Commit flushes the batch instead.
+				pool := db.Statement.ConnPool
+				var tx *sql.Tx
+				if sqlDB, ok := pool.(*sql.DB); ok {
+					var err error
+					tx, err = sqlDB.BeginTx(db.Statement.Context, nil)
+					if db.AddError(err) != nil {
+						return
+					}
+					pool = tx
+				}
+
+				stmt, err := pool.PrepareContext(db.Statement.Context, db.Statement.SQL.String())
+				if db.AddError(err) != nil {
+					if tx != nil {
+						tx.Rollback()
+					}
+					return
+				}
+
+				for _, value := range values.Values {
+					if _, err := stmt.Exec(value...); db.AddError(err) != nil {
+						stmt.Close()
+						if tx != nil {
+							tx.Rollback()
+						}
+						return
+					}
+				}
+				stmt.Close()
+
+				if tx != nil {
+					db.AddError(tx.Commit())
+				}
+				return
+			} else {
+				db.Statement.AddClause(values)
+				db.Statement.Build(db.Statement.BuildClauses...)
+			}
+		}
+
+		if !db.DryRun && db.Error == nil {
+			_, err := db.Statement.ConnPool.ExecContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
+			db.AddError(err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestInsertSettings(t *testing.T) {
+	tests := []struct {
+		name                   string
+		asyncInsert, syncWrite bool
+		want                   chv2Settings
+	}{
+		{"neither", false, false, chv2Settings{}},
+		{"async only", true, false, chv2Settings{"async_insert": 1, "wait_for_async_insert": 0}},
+		{"sync only", false, true, chv2Settings{"insert_distributed_sync": 1}},
+		{"both", true, true, chv2Settings{"async_insert": 1, "wait_for_async_insert": 0, "insert_distributed_sync": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := insertSettings(tt.asyncInsert, tt.syncWrite)
+			if len(got) != len(tt.want) {
+				t.Fatalf("insertSettings(%v, %v) = %v, want %v", tt.asyncInsert, tt.syncWrite, got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("insertSettings(%v, %v)[%q] = %v, want %v", tt.asyncInsert, tt.syncWrite, k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// chv2Settings mirrors chv2.Settings so the table above doesn't need to
+// import the driver package just to spell out a map literal.
+type chv2Settings = map[string]interface{}
+
+// TestAsyncInsertEnabled is the regression test for a bug where
+// asyncInsertEnabled's predecessor asserted db.Config.Dialector against the
+// non-pointer Dialector type, which New and Open never return - so
+// Config.AsyncInsert could never actually take effect through the public
+// API. It builds dialectors the same way a caller would, via New/Open, and
+// checks the assertion actually succeeds for them.
+func TestAsyncInsertEnabled(t *testing.T) {
+	if got := asyncInsertEnabled(New(Config{AsyncInsert: true})); !got {
+		t.Error("asyncInsertEnabled(New(Config{AsyncInsert: true})) = false, want true")
+	}
+	if got := asyncInsertEnabled(New(Config{AsyncInsert: false})); got {
+		t.Error("asyncInsertEnabled(New(Config{AsyncInsert: false})) = true, want false")
+	}
+	if got := asyncInsertEnabled(Open("tcp://localhost:9000")); got {
+		t.Error("asyncInsertEnabled(Open(...)) = true, want false (Open never sets AsyncInsert)")
+	}
+}
+
+// batchRecorder is a database/sql/driver fake that stands in for
+// clickhouse-go/v2's std driver, recording the query and context every
+// PrepareContext call receives so the test can check both that the query
+// text no longer carries a string-concatenated SETTINGS clause and that the
+// context reaching the "driver" is still derived from the caller's context
+// rather than a detached context.Background().
+type batchRecorder struct {
+	mu       sync.Mutex
+	queries  []string
+	contexts []context.Context
+}
+
+func (r *batchRecorder) record(ctx context.Context, query string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = append(r.queries, query)
+	r.contexts = append(r.contexts, ctx)
+}
+
+func (r *batchRecorder) Open(name string) (driver.Conn, error) {
+	return &fakeConn{recorder: r}, nil
+}
+
+type fakeConn struct {
+	recorder *batchRecorder
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *fakeConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	c.recorder.record(ctx, query)
+	return &fakeStmt{}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("fakeStmt: Query not supported")
+}
+func (fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type batchTestRow struct {
+	ID   uint
+	Name string
+}
+
+type sentinelKey struct{}
+
+// TestCreateBatchSettingsReachContext is the regression test for the bug
+// where AsyncInsert/insert_distributed_sync were appended as a SQL
+// "SETTINGS ..." clause after the VALUES placeholders of a batched, multi-row
+// INSERT - text the clickhouse-go/v2 std driver silently discards when it
+// normalizes the prepared statement. It asserts the generated SQL no longer
+// carries that trailing clause, and that the context reaching PrepareContext
+// both exists (so chv2.Context had something to attach settings to) and is
+// still derived from the statement's own context rather than a detached one.
+func TestCreateBatchSettingsReachContext(t *testing.T) {
+	recorder := &batchRecorder{}
+	driverName := "clickhouse-create-test-" + t.Name()
+	sql.Register(driverName, recorder)
+
+	conn, err := sql.Open(driverName, "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer conn.Close()
+
+	dialector := New(Config{
+		Conn:                      conn,
+		SkipInitializeWithVersion: true,
+		AsyncInsert:               true,
+	})
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), sentinelKey{}, "from-caller")
+	rows := []batchTestRow{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	if err := db.WithContext(ctx).Create(&rows).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if len(recorder.queries) != 1 {
+		t.Fatalf("got %d PrepareContext calls, want 1: %v", len(recorder.queries), recorder.queries)
+	}
+	if strings.Contains(recorder.queries[0], "SETTINGS") {
+		t.Errorf("batched INSERT query still has a string-concatenated SETTINGS clause, which the driver silently drops: %q", recorder.queries[0])
+	}
+
+	gotCtx := recorder.contexts[0]
+	if gotCtx == nil {
+		t.Fatal("PrepareContext received a nil context")
+	}
+	if v, _ := gotCtx.Value(sentinelKey{}).(string); v != "from-caller" {
+		t.Errorf("PrepareContext's context lost the caller's value chain, got %q, want %q", v, "from-caller")
+	}
+}
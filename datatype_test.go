@@ -0,0 +1,294 @@
+package clickhouse
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/schema"
+)
+
+type dataTypeModel struct {
+	Flag      bool
+	Small     int8
+	Count     int
+	Unsigned  uint32
+	Price     float32
+	PreciseAt float64 `gorm:"precision:4;scale:2"`
+	Name      string
+	Code      string `gorm:"size:8"`
+	Payload   []byte
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Host      net.IP
+	Nickname  *string
+}
+
+func parseFieldsByDBName(t *testing.T) map[string]*schema.Field {
+	t.Helper()
+	s, err := schema.Parse(&dataTypeModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+	return s.FieldsByDBName
+}
+
+func TestDataTypeOf(t *testing.T) {
+	fields := parseFieldsByDBName(t)
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"flag", "UInt8"},
+		{"small", "Int8"},
+		{"count", "Int64"},
+		{"unsigned", "UInt32"},
+		{"price", "Float32"},
+		{"precise_at", "decimal(4, 2)"},
+		{"name", "String"},
+		{"code", "FixedString(8)"},
+		{"payload", "String"},
+		// uuid.UUID unwraps to schema.String and net.IP collapses to
+		// schema.Bytes inside schema.Parse itself (see DataTypeOf's
+		// schema.String, schema.Bytes case) - these two prove the
+		// TypeMapper still gets a say for those named types even though
+		// their DataType is no longer empty by the time DataTypeOf sees them.
+		{"id", "UUID"},
+		{"host", "IPv6"},
+		{"nickname", "Nullable(String)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			field, ok := fields[tt.field]
+			if !ok {
+				t.Fatalf("no parsed field named %q", tt.field)
+			}
+			if got := d.DataTypeOf(field); got != tt.want {
+				t.Errorf("DataTypeOf(%s) = %q, want %q", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDataTypeOfUntaggedSliceIsARelationNotAColumn documents a genuine GORM
+// limitation rather than papering over it: a bare []string/map[string]V
+// field is treated as a relation and fails schema.Parse entirely (GORM has
+// no opt-out for relation detection other than an explicit `gorm:"type:..."`
+// tag), so DefaultTypeMapper's Array/Map branches never run for it in
+// practice. TestArrayColumnViaExplicitTypeTag below shows the supported way
+// to declare one.
+func TestDataTypeOfUntaggedSliceIsARelationNotAColumn(t *testing.T) {
+	type untagged struct {
+		Tags []string
+	}
+	if _, err := schema.Parse(&untagged{}, &sync.Map{}, schema.NamingStrategy{}); err == nil {
+		t.Fatal("schema.Parse succeeded for an untagged []string field, want it to fail as an unresolvable relation")
+	}
+}
+
+// TestArrayColumnViaExplicitTypeTag is the supported way to declare an
+// Array/Map column: the literal `gorm:"type:..."` tag sets field.DataType
+// directly, so schema.Parse never attempts to treat the field as a relation.
+func TestArrayColumnViaExplicitTypeTag(t *testing.T) {
+	type tagged struct {
+		Tags  []string         `gorm:"type:Array(String)"`
+		Score map[string]int32 `gorm:"type:Map(String, Int32)"`
+	}
+	s, err := schema.Parse(&tagged{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["tags"]), "Array(String)"; got != want {
+		t.Errorf("DataTypeOf(tags) = %q, want %q", got, want)
+	}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["score"]), "Map(String, Int32)"; got != want {
+		t.Errorf("DataTypeOf(score) = %q, want %q", got, want)
+	}
+}
+
+// TestDataTypeOfTypeMapperFallback exercises DefaultTypeMapper's Array and
+// Nullable branches directly via a hand-built *schema.Field, since a real
+// model slice field can't get through schema.Parse without the literal type
+// tag covered by TestArrayColumnViaExplicitTypeTag above.
+func TestDataTypeOfTypeMapperFallback(t *testing.T) {
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+
+	tests := []struct {
+		name      string
+		fieldType reflect.Type
+		want      string
+	}{
+		{"slice of string", reflect.TypeOf([]string{}), "Array(String)"},
+		{"pointer to string", reflect.TypeOf(new(string)), "Nullable(String)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := &schema.Field{FieldType: tt.fieldType}
+			if got := d.DataTypeOf(field); got != tt.want {
+				t.Errorf("DataTypeOf(%s) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDataTypeOfEnumAndDecimalTagsOnIntFields is the regression test for a bug
+// where DataTypeOf only consulted the TypeMapper inside its String/Bytes case
+// (and its empty-DataType fallback), so a `gorm:"enum8:..."` or
+// `gorm:"precision:...;scale:..."` tag on a Bool/Int/Uint field - the natural
+// Go representation of a ClickHouse enum or a non-float decimal column - was
+// silently ignored: DataTypeOf's Bool/Int/Uint case returned its plain
+// UInt8/IntN rendering without ever giving the TypeMapper a say.
+func TestDataTypeOfEnumAndDecimalTagsOnIntFields(t *testing.T) {
+	type taggedIntModel struct {
+		Status int8  `gorm:"enum8:active=1,inactive=2"`
+		Count  int64 `gorm:"precision:10;scale:2"`
+	}
+	s, err := schema.Parse(&taggedIntModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["status"]), "Enum8('active' = 1, 'inactive' = 2)"; got != want {
+		t.Errorf("DataTypeOf(status) = %q, want %q", got, want)
+	}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["count"]), "decimal(10, 2)"; got != want {
+		t.Errorf("DataTypeOf(count) = %q, want %q", got, want)
+	}
+}
+
+// TestDataTypeOfTimePrecisionTagNotDecimal is the regression test for a bug
+// where DefaultTypeMapper's decimal-from-precision-tag branch fired for any
+// field with Precision>0 other than schema.Float, so a time.Time field's
+// precision tag (DateTime64 sub-second digits) was misread as a request for
+// a decimal column instead.
+func TestDataTypeOfTimePrecisionTagNotDecimal(t *testing.T) {
+	type timePrecisionModel struct {
+		CreatedAt time.Time `gorm:"precision:6"`
+	}
+	s, err := schema.Parse(&timePrecisionModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["created_at"]), "DateTime64(6)"; got != want {
+		t.Errorf("DataTypeOf(created_at) = %q, want %q", got, want)
+	}
+}
+
+// namedCode is a named string type, used to prove DefaultTypeMapper declines
+// for string-kind fields regardless of whether the Go type is named.
+type namedCode string
+
+// TestDataTypeOfNamedStringTypeKeepsSize is the regression test for a bug
+// where DefaultTypeMapper's decline guard only fired for unnamed string
+// types (PkgPath() == ""), so a named string type with a `gorm:"size:N"` tag
+// fell through to DefaultTypeMapper's own generic "String" rendering instead
+// of leaving DataTypeOf's Size-aware FixedString(N) logic in charge.
+func TestDataTypeOfNamedStringTypeKeepsSize(t *testing.T) {
+	type namedStringModel struct {
+		Code namedCode `gorm:"size:8"`
+	}
+	s, err := schema.Parse(&namedStringModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["code"]), "FixedString(8)"; got != want {
+		t.Errorf("DataTypeOf(code) = %q, want %q", got, want)
+	}
+}
+
+// namedInt32 is a named int32 type, used to prove DefaultTypeMapper declines
+// for scalar-kind fields regardless of whether the Go type is named.
+type namedInt32 int32
+
+// TestDataTypeOfNamedNumericTypeKeepsSize is the regression test for a bug
+// where DefaultTypeMapper's decline guard only fired for composite-vs-scalar
+// shape via string/PkgPath checks, missing named scalar numeric types: a
+// `type UserID int32` field with a `gorm:"size:16"` tag fell through to
+// DefaultTypeMapper's bare Kind-based "Int32" rendering instead of leaving
+// DataTypeOf's Size-aware Int8/Int16/Int32/Int64 logic in charge.
+func TestDataTypeOfNamedNumericTypeKeepsSize(t *testing.T) {
+	type namedNumericModel struct {
+		UserID namedInt32 `gorm:"size:16"`
+	}
+	s, err := schema.Parse(&namedNumericModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["user_id"]), "Int16"; got != want {
+		t.Errorf("DataTypeOf(user_id) = %q, want %q", got, want)
+	}
+}
+
+// TestDataTypeOfExplicitTypeTagOverridesDefaultArrayRendering is the
+// regression test for a bug where a composite (slice/map) field's explicit
+// `gorm:"type:..."` tag was silently replaced by DefaultTypeMapper's generic
+// Array/Map rendering once DataTypeOf started consulting the TypeMapper
+// unconditionally - e.g. a deliberate Array(LowCardinality(String)) tag would
+// have been overwritten with the plain Array(String) DefaultTypeMapper builds
+// from the field's Go type.
+func TestDataTypeOfExplicitTypeTagOverridesDefaultArrayRendering(t *testing.T) {
+	type customArrayModel struct {
+		Tags []string `gorm:"type:Array(LowCardinality(String))"`
+	}
+	s, err := schema.Parse(&customArrayModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["tags"]), "Array(LowCardinality(String))"; got != want {
+		t.Errorf("DataTypeOf(tags) = %q, want %q", got, want)
+	}
+}
+
+// TestDataTypeOfExplicitTypeTagCollidingWithDataTypeKeyword is the regression
+// test for a bug where the explicit-tag decline guard inferred "this field
+// has a literal gorm:"type:..." tag" from field.DataType not matching one of
+// GORM's own DataType constants - which breaks for a tag whose value happens
+// to spell one of those constants (e.g. `gorm:"type:string"` deliberately
+// flattening a slice into a single column), since GORM resolves that tag to
+// exactly schema.String, indistinguishable by value from an untagged field.
+func TestDataTypeOfExplicitTypeTagCollidingWithDataTypeKeyword(t *testing.T) {
+	type flattenedModel struct {
+		Tags []string `gorm:"type:string"`
+	}
+	s, err := schema.Parse(&flattenedModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+
+	d := Dialector{Config: &Config{TypeMapper: DefaultTypeMapper}}
+	if got, want := d.DataTypeOf(s.FieldsByDBName["tags"]), "String"; got != want {
+		t.Errorf("DataTypeOf(tags) = %q, want %q", got, want)
+	}
+}
+
+func TestDataTypeOfTimePrecision(t *testing.T) {
+	fields := parseFieldsByDBName(t)
+	field := fields["created_at"]
+
+	d := Dialector{Config: &Config{}}
+	if got, want := d.DataTypeOf(field), "DateTime64(3)"; got != want {
+		t.Errorf("DataTypeOf(created_at) = %q, want %q", got, want)
+	}
+
+	d = Dialector{Config: &Config{DisableDatetimePrecision: true}}
+	field.Precision = 0
+	if got, want := d.DataTypeOf(field), "DateTime64"; got != want {
+		t.Errorf("DataTypeOf(created_at) with DisableDatetimePrecision = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,15 @@
+package clickhouse
+
+import (
+	"github.com/vpol/clickhouse/migrations"
+	"gorm.io/gorm"
+)
+
+// NewMigrator re-exports migrations.NewMigrator so callers reach it as
+// clickhouse.NewMigrator without an extra import alongside Dialector.Migrator.
+// See the migrations package for Migration, Options, and the helpers
+// (ExecMutation, RenameColumn, CreateMaterializedView, CreateDictionary) it
+// needs to work with ClickHouse's async mutations and DDL quirks.
+func NewMigrator(db *gorm.DB, migs []*migrations.Migration, opts migrations.Options) *migrations.Migrator {
+	return migrations.NewMigrator(db, migs, opts)
+}
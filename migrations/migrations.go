@@ -0,0 +1,288 @@
+// Package migrations implements a gormigrate-style migration runner aware of
+// ClickHouse's DDL quirks: ALTER-triggered mutations are asynchronous, RENAME
+// COLUMN is only supported on newer servers, and ON CLUSTER has to be
+// threaded through every statement in a replicated setup. It's meant to slot
+// in alongside this module's Dialector.Migrator, which only ever creates and
+// alters tables to match a gorm.Model - anything versioned and repeatable
+// (backfills, materialized views, dictionaries) belongs here instead.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoAppliedMigrations is returned by RollbackLast when the
+// schema_migrations table has no recorded migrations to roll back.
+var ErrNoAppliedMigrations = errors.New("migrations: no applied migrations to roll back")
+
+// Migration is a single, idempotent schema change. ID must be stable and
+// unique across the lifetime of the migration set - it's the value recorded
+// in schema_migrations - and migrations are applied in the order they're
+// passed to NewMigrator, not sorted by ID.
+type Migration struct {
+	ID       string
+	Migrate  func(*gorm.DB) error
+	Rollback func(*gorm.DB) error
+}
+
+// Options configures a Migrator.
+type Options struct {
+	// TableName is the ClickHouse table used to record applied migration
+	// IDs. Defaults to "schema_migrations".
+	TableName string
+	// Cluster, when set, is appended as "ON CLUSTER <Cluster>" to every DDL
+	// statement the Migrator issues itself (schema_migrations, RenameColumn,
+	// CreateMaterializedView, CreateDictionary). Migration.Migrate functions
+	// that issue their own DDL need to apply it themselves.
+	Cluster string
+	// SupportsRenameColumn gates RenameColumn's fast path. Leave it false
+	// unless the connected server is known to be ClickHouse >= 20.4, e.g. by
+	// passing !dialector.DontSupportRenameColumn once Dialector.Initialize
+	// has run.
+	SupportsRenameColumn bool
+	// MutationPollInterval is how often ExecMutation polls system.mutations.
+	// Defaults to 500ms.
+	MutationPollInterval time.Duration
+	// MutationPollTimeout bounds how long ExecMutation waits for a mutation
+	// to finish before giving up. Defaults to 5 minutes.
+	MutationPollTimeout time.Duration
+}
+
+// Migrator applies and tracks Migrations against a ClickHouse-backed
+// gorm.DB, recording applied IDs in a ReplacingMergeTree table so replays
+// are idempotent.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+	opts       Options
+}
+
+// NewMigrator builds a Migrator for the given migrations, applied in order.
+func NewMigrator(db *gorm.DB, migrations []*Migration, opts Options) *Migrator {
+	if opts.TableName == "" {
+		opts.TableName = "schema_migrations"
+	}
+	if opts.MutationPollInterval <= 0 {
+		opts.MutationPollInterval = 500 * time.Millisecond
+	}
+	if opts.MutationPollTimeout <= 0 {
+		opts.MutationPollTimeout = 5 * time.Minute
+	}
+	return &Migrator{db: db, migrations: migrations, opts: opts}
+}
+
+// Run applies every migration that isn't already recorded in
+// schema_migrations, in order, stopping at the first error.
+func (m *Migrator) Run(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+
+	if err := m.ensureSchemaTable(db); err != nil {
+		return fmt.Errorf("migrations: creating %s: %w", m.opts.TableName, err)
+	}
+
+	applied, err := m.appliedIDs(db)
+	if err != nil {
+		return fmt.Errorf("migrations: reading %s: %w", m.opts.TableName, err)
+	}
+
+	for _, mig := range m.migrations {
+		if applied[mig.ID] {
+			continue
+		}
+		if err := mig.Migrate(db); err != nil {
+			return fmt.Errorf("migrations: %s: %w", mig.ID, err)
+		}
+		if err := db.Exec(
+			fmt.Sprintf("INSERT INTO %s (id) VALUES (?)", quoteIdent(m.opts.TableName)),
+			mig.ID,
+		).Error; err != nil {
+			return fmt.Errorf("migrations: %s: recording as applied: %w", mig.ID, err)
+		}
+	}
+	return nil
+}
+
+// RollbackLast runs the Rollback function of the most recently applied
+// migration (by position in the set passed to NewMigrator, not insertion
+// time) and removes it from schema_migrations.
+func (m *Migrator) RollbackLast(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+
+	applied, err := m.appliedIDs(db)
+	if err != nil {
+		return fmt.Errorf("migrations: reading %s: %w", m.opts.TableName, err)
+	}
+
+	var last *Migration
+	for _, mig := range m.migrations {
+		if applied[mig.ID] {
+			last = mig
+		}
+	}
+	if last == nil {
+		return ErrNoAppliedMigrations
+	}
+	if last.Rollback == nil {
+		return fmt.Errorf("migrations: %s has no Rollback", last.ID)
+	}
+
+	if err := last.Rollback(db); err != nil {
+		return fmt.Errorf("migrations: rolling back %s: %w", last.ID, err)
+	}
+
+	return m.ExecMutation(ctx, db, m.opts.TableName,
+		fmt.Sprintf("ALTER TABLE %s%s DELETE WHERE id = ?", quoteIdent(m.opts.TableName), m.onCluster()),
+		last.ID,
+	)
+}
+
+func (m *Migrator) ensureSchemaTable(db *gorm.DB) error {
+	return db.Exec(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s%s (id String, applied_at DateTime DEFAULT now()) ENGINE = ReplacingMergeTree() ORDER BY id",
+		quoteIdent(m.opts.TableName), m.onCluster(),
+	)).Error
+}
+
+func (m *Migrator) appliedIDs(db *gorm.DB) (map[string]bool, error) {
+	rows, err := db.Raw(fmt.Sprintf("SELECT id FROM %s FINAL", quoteIdent(m.opts.TableName))).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// ExecMutation runs ddl (an ALTER ... UPDATE/DELETE/MATERIALIZE COLUMN, or
+// any other statement that triggers a ClickHouse mutation) and waits for it
+// to finish before returning, so migrations see the same guarantee a
+// synchronous ALTER gives on most other databases. It appends
+// `SETTINGS mutations_sync = 2` when ddl doesn't already carry a SETTINGS
+// clause, then polls system.mutations as a backstop in case the server or an
+// in-between proxy silently ignores that setting.
+func (m *Migrator) ExecMutation(ctx context.Context, db *gorm.DB, table, ddl string, vars ...interface{}) error {
+	db = db.WithContext(ctx)
+
+	if !strings.Contains(strings.ToUpper(ddl), "SETTINGS") {
+		ddl += " SETTINGS mutations_sync = 2"
+	}
+	if err := db.Exec(ddl, vars...).Error; err != nil {
+		return err
+	}
+	return m.waitForMutations(ctx, db, table)
+}
+
+// waitForMutationsQuery scopes the system.mutations poll to the current
+// database, matching HasTable/HasColumn in the parent package's Migrator -
+// without it, a same-named table in another database could block on (or
+// misreport a failure from) a mutation that has nothing to do with this one.
+const waitForMutationsQuery = "SELECT count(), any(latest_fail_reason) FROM system.mutations " +
+	"WHERE database = currentDatabase() AND table = ? AND is_done = 0"
+
+func (m *Migrator) waitForMutations(ctx context.Context, db *gorm.DB, table string) error {
+	deadline := time.Now().Add(m.opts.MutationPollTimeout)
+	for {
+		var pending int64
+		var failReason string
+		err := db.Raw(waitForMutationsQuery, table).Row().Scan(&pending, &failReason)
+		if err != nil {
+			return fmt.Errorf("polling system.mutations for %s: %w", table, err)
+		}
+		if failReason != "" {
+			return fmt.Errorf("mutation on %s failed: %s", table, failReason)
+		}
+		if pending == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for mutations on %s to finish", m.opts.MutationPollTimeout, table)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.opts.MutationPollInterval):
+		}
+	}
+}
+
+// RenameColumn renames a column. When Options.SupportsRenameColumn is set it
+// uses the native RENAME COLUMN; otherwise - since that statement is
+// rejected outright on ClickHouse < 20.4 - it rewrites the operation into
+// add-column, backfill, drop-column: adding newName, copying every row's
+// oldName into it via an ALTER ... UPDATE mutation, then dropping oldName.
+func (m *Migrator) RenameColumn(ctx context.Context, db *gorm.DB, table, oldName, newName, columnType string) error {
+	db = db.WithContext(ctx)
+	cluster := m.onCluster()
+
+	if m.opts.SupportsRenameColumn {
+		return db.Exec(fmt.Sprintf("ALTER TABLE %s%s RENAME COLUMN %s TO %s",
+			quoteIdent(table), cluster, quoteIdent(oldName), quoteIdent(newName))).Error
+	}
+
+	addSQL := fmt.Sprintf("ALTER TABLE %s%s ADD COLUMN IF NOT EXISTS %s %s",
+		quoteIdent(table), cluster, quoteIdent(newName), columnType)
+	if err := db.Exec(addSQL).Error; err != nil {
+		return err
+	}
+
+	backfillSQL := fmt.Sprintf("ALTER TABLE %s%s UPDATE %s = %s WHERE 1",
+		quoteIdent(table), cluster, quoteIdent(newName), quoteIdent(oldName))
+	if err := m.ExecMutation(ctx, db, table, backfillSQL); err != nil {
+		return err
+	}
+
+	dropSQL := fmt.Sprintf("ALTER TABLE %s%s DROP COLUMN %s", quoteIdent(table), cluster, quoteIdent(oldName))
+	return db.Exec(dropSQL).Error
+}
+
+// CreateMaterializedView creates a materialized view named name that feeds
+// rows matching query into toTable as they're inserted into its source
+// table(s).
+func (m *Migrator) CreateMaterializedView(ctx context.Context, db *gorm.DB, name, toTable, query string) error {
+	return db.WithContext(ctx).Exec(fmt.Sprintf(
+		"CREATE MATERIALIZED VIEW IF NOT EXISTS %s%s TO %s AS %s",
+		quoteIdent(name), m.onCluster(), quoteIdent(toTable), query,
+	)).Error
+}
+
+// CreateDictionary creates a dictionary named name. body is the raw clause
+// text following the name - column list, PRIMARY KEY, SOURCE, LAYOUT,
+// LIFETIME - since dictionary definitions vary too widely to template.
+func (m *Migrator) CreateDictionary(ctx context.Context, db *gorm.DB, name, body string) error {
+	return db.WithContext(ctx).Exec(fmt.Sprintf(
+		"CREATE DICTIONARY IF NOT EXISTS %s%s %s",
+		quoteIdent(name), m.onCluster(), body,
+	)).Error
+}
+
+func (m *Migrator) onCluster() string {
+	if m.opts.Cluster == "" {
+		return ""
+	}
+	return fmt.Sprintf(" ON CLUSTER %s", m.opts.Cluster)
+}
+
+// quoteIdent backtick-quotes each dot-separated part of name, matching
+// Dialector.QuoteTo in the parent package.
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = "`" + p + "`"
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWaitForMutationsQueryScopesToCurrentDatabase(t *testing.T) {
+	for _, want := range []string{"database = currentDatabase()", "table = ?", "is_done = 0"} {
+		if !strings.Contains(waitForMutationsQuery, want) {
+			t.Errorf("waitForMutationsQuery = %q, want it to contain %q", waitForMutationsQuery, want)
+		}
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"schema_migrations", "`schema_migrations`"},
+		{"db.events", "`db`.`events`"},
+	}
+	for _, tt := range tests {
+		if got := quoteIdent(tt.name); got != tt.want {
+			t.Errorf("quoteIdent(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMigratorOnCluster(t *testing.T) {
+	m := NewMigrator(nil, nil, Options{})
+	if got := m.onCluster(); got != "" {
+		t.Errorf("onCluster() with no Cluster = %q, want empty", got)
+	}
+
+	m = NewMigrator(nil, nil, Options{Cluster: "c1"})
+	if got, want := m.onCluster(), " ON CLUSTER c1"; got != want {
+		t.Errorf("onCluster() = %q, want %q", got, want)
+	}
+}
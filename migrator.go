@@ -0,0 +1,471 @@
+package clickhouse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// Errors enumeration
+var (
+	ErrRenameColumnUnsupported = errors.New("renaming column is not supported in your clickhouse version < 20.4")
+	ErrRenameIndexUnsupported  = errors.New("renaming index is not supported")
+	ErrCreateIndexFailed       = errors.New("failed to create index with name")
+)
+
+type Migrator struct {
+	migrator.Migrator
+	Dialector
+}
+
+// Database
+
+func (m Migrator) CurrentDatabase() (name string) {
+	m.DB.Raw("SELECT currentDatabase()").Row().Scan(&name)
+	return
+}
+
+func (m Migrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
+	// Infer the ClickHouse datatype from schema.Field information
+	expr.SQL = m.Migrator.DataTypeOf(field)
+
+	// NOTE:
+	// NULL and UNIQUE keyword is not supported in clickhouse.
+	// Hence, skipping checks for field.Unique and field.NotNull
+
+	// Build DEFAULT clause after DataTypeOf() expression optionally
+	if field.HasDefaultValue && (field.DefaultValueInterface != nil || field.DefaultValue != "") {
+		if field.DefaultValueInterface != nil {
+			defaultStmt := &gorm.Statement{Vars: []interface{}{field.DefaultValueInterface}}
+			m.Dialector.BindVarTo(defaultStmt, defaultStmt, field.DefaultValueInterface)
+			expr.SQL += " DEFAULT " + m.Dialector.Explain(defaultStmt.SQL.String(), field.DefaultValueInterface)
+		} else if field.DefaultValue != "(-)" {
+			expr.SQL += " DEFAULT " + field.DefaultValue
+		}
+	}
+
+	// Build COMMENT clause optionally after DEFAULT
+	if comment, ok := field.TagSettings["COMMENT"]; ok {
+		expr.SQL += " COMMENT " + m.Dialector.Explain("?", comment)
+	}
+
+	// Build CODEC compression algorithm optionally
+	// NOTE: the codec algo name is case sensitive!
+	if codecstr, ok := field.TagSettings["CODEC"]; ok && codecstr != "" {
+		codecArgsSQL := m.Dialector.DefaultCompression
+		if codecstr != "" {
+			codecArgsSQL = codecstr
+		}
+		expr.SQL += fmt.Sprintf(" CODEC(%s) ", codecArgsSQL)
+	}
+
+	return expr
+}
+
+// Tables
+
+func (m Migrator) CreateTable(models ...interface{}) error {
+	for _, model := range m.ReorderModels(models, false) {
+		tx := m.DB.Session(new(gorm.Session))
+		if dt, ok := model.(DistributedTabler); ok {
+			if err := m.RunWithValue(model, func(stmt *gorm.Statement) error {
+				return m.createDistributedTable(tx, model, stmt, dt)
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := m.RunWithValue(model, func(stmt *gorm.Statement) error {
+			return m.createLocalTable(tx, model, stmt, stmt.Table)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createLocalTable builds and executes the CREATE TABLE statement for model
+// under tableName - stmt.Table for an ordinary table, or "<table>_local" for
+// the local table backing a DistributedTabler model.
+func (m Migrator) createLocalTable(tx *gorm.DB, model interface{}, stmt *gorm.Statement, tableName string) (err error) {
+	var (
+		createTableSQL = "CREATE TABLE ?%s (%s %s %s) %s"
+		args           = []interface{}{clause.Table{Name: tableName}}
+	)
+
+	// Step 1. Build column datatype SQL string
+	columnSlice := make([]string, 0, len(stmt.Schema.DBNames))
+	for _, dbName := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[dbName]
+		columnSlice = append(columnSlice, "? ?")
+		args = append(args,
+			clause.Column{Name: dbName},
+			m.FullDataTypeOf(field),
+		)
+	}
+	columnStr := strings.Join(columnSlice, ",")
+
+	// Step 2. Build constraint check SQL string if any constraint
+	constrSlice := make([]string, 0, len(columnSlice))
+	for _, check := range stmt.Schema.ParseCheckConstraints() {
+		constrSlice = append(constrSlice, "CONSTRAINT ? CHECK ?")
+		args = append(args,
+			clause.Column{Name: check.Name},
+			clause.Expr{SQL: check.Constraint},
+		)
+	}
+	constrStr := strings.Join(constrSlice, ",")
+	if len(constrSlice) > 0 {
+		constrStr = ", " + constrStr
+	}
+
+	// Step 3. Build index SQL string
+	// NOTE: clickhouse does not support for index class.
+	indexSlice := make([]string, 0, 10)
+	for _, index := range stmt.Schema.ParseIndexes() {
+		if m.CreateIndexAfterCreateTable {
+			defer func(model interface{}, indexName string) {
+				// TODO: what if there are multiple errors
+				// when creating indices after create table?
+				err = tx.Migrator().CreateIndex(model, indexName)
+			}(model, index.Name)
+			continue
+		}
+		// Get indexing type `gorm:"index,type:minmax"`
+		// Choice: minmax | set(n) | ngrambf_v1(n, size, hash, seed) | bloomfilter()
+		indexType := m.Dialector.DefaultIndexType
+		if index.Type != "" {
+			indexType = index.Type
+		}
+
+		// Get expression for index options
+		// Syntax: (`colname1`, ...)
+		buildIndexOptions := tx.Migrator().(migrator.BuildIndexOptionsInterface)
+		indexOptions := buildIndexOptions.BuildIndexOptions(index.Fields, stmt)
+
+		str := fmt.Sprintf("INDEX ? ? TYPE %s GRANULARITY %d", indexType, m.getIndexGranularityOption(index.Fields))
+		indexSlice = append(indexSlice, str)
+		args = append(args, clause.Expr{SQL: index.Name}, indexOptions)
+	}
+	indexStr := strings.Join(indexSlice, ", ")
+	if len(indexSlice) > 0 {
+		indexStr = ", " + indexStr
+	}
+
+	// Step 4. Finally assemble CREATE TABLE ... SQL string
+	engineOpts := m.Dialector.DefaultTableEngineOpts
+	if tableOption, ok := m.DB.Get("gorm:table_options"); ok {
+		engineOpts = fmt.Sprint(tableOption)
+	}
+
+	var engine TableEngine
+	var tableOpts TableOptions
+	var hasCustomEngine bool
+	if tabler, ok := model.(ClickHouseTabler); ok {
+		engine, tableOpts = tabler.ClickHouseTable()
+		hasCustomEngine = true
+	}
+	tagOpts := tableOptionsFromTags(stmt.Schema.DBNames, stmt.Schema.FieldsByDBName)
+	tableOpts = mergeTableOptions(tableOpts, tagOpts)
+
+	if hasCustomEngine || !tableOpts.isZero() {
+		if engine == nil {
+			engine = MergeTree{}
+		}
+		engineOpts = tableOpts.clause(engine)
+	}
+
+	createTableSQL = fmt.Sprintf(createTableSQL, m.onCluster(), columnStr, constrStr, indexStr, engineOpts)
+
+	err = tx.Exec(createTableSQL, args...).Error
+
+	return
+}
+
+// createDistributedTable creates the local table a DistributedTabler model
+// is backed by, named "<table>_local", then wraps it in a Distributed table
+// under the model's own table name - dt.DistributedTable supplies the
+// Distributed engine's Cluster/Database/ShardingKey; LocalTable and Database
+// default to the local table just created and CurrentDatabase when left
+// empty.
+func (m Migrator) createDistributedTable(tx *gorm.DB, model interface{}, stmt *gorm.Statement, dt DistributedTabler) error {
+	localTable := stmt.Table + "_local"
+	if err := m.createLocalTable(tx, model, stmt, localTable); err != nil {
+		return err
+	}
+
+	dist := dt.DistributedTable()
+	if dist.LocalTable == "" {
+		dist.LocalTable = localTable
+	}
+	if dist.Database == "" {
+		dist.Database = m.CurrentDatabase()
+	}
+
+	distSQL := fmt.Sprintf("CREATE TABLE ?%s AS ? ENGINE=%s", m.onCluster(), dist.Engine())
+	return tx.Exec(distSQL, clause.Table{Name: stmt.Table}, clause.Table{Name: localTable}).Error
+}
+
+func (m Migrator) HasTable(value interface{}) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentDatabase := m.DB.Migrator().CurrentDatabase()
+		return m.DB.Raw(
+			"SELECT count(*) FROM system.tables WHERE database = ? AND name = ? AND is_temporary = ?",
+			currentDatabase,
+			stmt.Table,
+			uint8(0)).Row().Scan(&count)
+	})
+	return count > 0
+}
+
+func (m Migrator) DropTable(values ...interface{}) error {
+	values = m.ReorderModels(values, false)
+	for i := len(values) - 1; i >= 0; i-- {
+		tx := m.DB.Session(&gorm.Session{})
+		value := values[i]
+		if _, ok := value.(DistributedTabler); ok {
+			if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+				return m.dropDistributedTable(tx, stmt)
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			return tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS ?%s", m.onCluster()), m.CurrentTable(stmt)).Error
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropDistributedTable drops both tables createDistributedTable creates for
+// a DistributedTabler model, in the reverse order they were created: the
+// Distributed wrapper (stmt.Table) first, then "<table>_local". Dropping the
+// wrapper first, not last, means a failure partway through never leaves the
+// Distributed table pointing at a local table that's already gone.
+func (m Migrator) dropDistributedTable(tx *gorm.DB, stmt *gorm.Statement) error {
+	if err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS ?%s", m.onCluster()), m.CurrentTable(stmt)).Error; err != nil {
+		return err
+	}
+	localTable := clause.Table{Name: stmt.Table + "_local"}
+	return tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS ?%s", m.onCluster()), localTable).Error
+}
+
+// RenameTable renames a table, e.g. RENAME TABLE users TO customers. oldName
+// and newName may each be a model value or a bare table name string.
+func (m Migrator) RenameTable(oldName, newName interface{}) error {
+	resolveTable := func(v interface{}) (interface{}, error) {
+		if name, ok := v.(string); ok {
+			return clause.Table{Name: name}, nil
+		}
+		stmt := &gorm.Statement{DB: m.DB}
+		if err := stmt.Parse(v); err != nil {
+			return nil, err
+		}
+		return m.CurrentTable(stmt), nil
+	}
+
+	oldTable, err := resolveTable(oldName)
+	if err != nil {
+		return err
+	}
+	newTable, err := resolveTable(newName)
+	if err != nil {
+		return err
+	}
+
+	return m.DB.Exec(fmt.Sprintf("RENAME TABLE ? TO ?%s", m.onCluster()), oldTable, newTable).Error
+}
+
+// Columns
+
+func (m Migrator) AddColumn(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			return m.DB.Exec(
+				fmt.Sprintf("ALTER TABLE ?%s ADD COLUMN ? ?", m.onCluster()),
+				clause.Table{Name: stmt.Table}, clause.Column{Name: field.DBName},
+				m.FullDataTypeOf(field),
+			).Error
+		}
+		return fmt.Errorf("failed to look up field with name: %s", name)
+	})
+}
+
+func (m Migrator) DropColumn(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			name = field.DBName
+		}
+		return m.DB.Exec(
+			fmt.Sprintf("ALTER TABLE ?%s DROP COLUMN ?", m.onCluster()),
+			clause.Table{Name: stmt.Table}, clause.Column{Name: name},
+		).Error
+	})
+}
+
+func (m Migrator) AlterColumn(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			return m.DB.Exec(
+				fmt.Sprintf("ALTER TABLE ?%s MODIFY COLUMN ? ?", m.onCluster()),
+				clause.Table{Name: stmt.Table},
+				clause.Column{Name: field.DBName},
+				m.FullDataTypeOf(field),
+			).Error
+		}
+		return fmt.Errorf("altercolumn() failed to look up column with name: %s", name)
+	})
+}
+
+// RenameColumn renames a column. Only supported on ClickHouse 20.4 and above.
+// See: https://github.com/ClickHouse/ClickHouse/issues/146
+func (m Migrator) RenameColumn(value interface{}, oldName, newName string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if m.Dialector.DontSupportRenameColumn {
+			return ErrRenameColumnUnsupported
+		}
+
+		var field *schema.Field
+		if f := stmt.Schema.LookUpField(oldName); f != nil {
+			oldName = f.DBName
+			field = f
+		}
+		if f := stmt.Schema.LookUpField(newName); f != nil {
+			newName = f.DBName
+			field = f
+		}
+		if field == nil {
+			return fmt.Errorf("renamecolumn() failed to look up column with name: %s", oldName)
+		}
+		return m.DB.Exec(
+			fmt.Sprintf("ALTER TABLE ?%s RENAME COLUMN ? TO ?", m.onCluster()),
+			clause.Table{Name: stmt.Table},
+			clause.Column{Name: oldName},
+			clause.Column{Name: newName},
+		).Error
+	})
+}
+
+func (m Migrator) HasColumn(value interface{}, field string) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentDatabase := m.DB.Migrator().CurrentDatabase()
+		name := field
+
+		if stmt.Schema != nil {
+			if f := stmt.Schema.LookUpField(field); f != nil {
+				name = f.DBName
+			}
+		}
+
+		return m.DB.Raw(
+			"SELECT count(*) FROM system.columns WHERE database = ? AND table = ? AND name = ?",
+			currentDatabase, stmt.Table, name,
+		).Row().Scan(&count)
+	})
+
+	return count > 0
+}
+
+// Indexes
+
+func (m Migrator) BuildIndexOptions(opts []schema.IndexOption, stmt *gorm.Statement) (results []interface{}) {
+	for _, indexOpt := range opts {
+		str := stmt.Quote(indexOpt.DBName)
+		if indexOpt.Expression != "" {
+			str = indexOpt.Expression
+		}
+		results = append(results, clause.Expr{SQL: str})
+	}
+	return
+}
+
+func (m Migrator) CreateIndex(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		index := stmt.Schema.LookIndex(name)
+		if index == nil {
+			return ErrCreateIndexFailed
+		}
+
+		opts := m.BuildIndexOptions(index.Fields, stmt)
+		values := []interface{}{
+			clause.Table{Name: stmt.Table},
+			clause.Column{Name: index.Name},
+			opts,
+		}
+
+		// Get indexing type `gorm:"index,type:minmax"`
+		// Choice: minmax | set(n) | ngrambf_v1(n, size, hash, seed) | bloomfilter()
+		indexType := m.Dialector.DefaultIndexType
+		if index.Type != "" {
+			indexType = index.Type
+		}
+
+		// NOTE: concept of UNIQUE | FULLTEXT | SPATIAL index
+		// is NOT supported in clickhouse
+		createIndexSQL := fmt.Sprintf("ALTER TABLE ?%s ADD INDEX ? ? TYPE %s GRANULARITY %d",
+			m.onCluster(), indexType, m.getIndexGranularityOption(index.Fields))
+		return m.DB.Exec(createIndexSQL, values...).Error
+	})
+}
+
+func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error {
+	return ErrRenameIndexUnsupported
+}
+
+func (m Migrator) DropIndex(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema != nil {
+			if idx := stmt.Schema.LookIndex(name); idx != nil {
+				name = idx.Name
+			}
+		}
+		return m.DB.Exec(fmt.Sprintf("ALTER TABLE ?%s DROP INDEX ?", m.onCluster()),
+			clause.Table{Name: stmt.Table},
+			clause.Column{Name: name}).Error
+	})
+}
+
+// Helpers
+
+// onCluster returns " ON CLUSTER <cluster>" when Config.Cluster is set, for
+// threading through every DDL statement Migrator issues, so schema changes
+// against a replicated/distributed setup apply to every node instead of just
+// the one gorm is connected to.
+func (m Migrator) onCluster() string {
+	if m.Dialector.Config.Cluster == "" {
+		return ""
+	}
+	return " ON CLUSTER " + m.Dialector.Config.Cluster
+}
+
+func (m Migrator) getIndexGranularityOption(opts []schema.IndexOption) int {
+	for _, indexOpt := range opts {
+		settingStr, ok := indexOpt.Field.TagSettings["INDEX"]
+		if !ok {
+			continue
+		}
+		// e.g. settingStr: "a,expression:u64*i32,type:minmax,granularity:3"
+		for _, str := range strings.Split(settingStr, ",") {
+			keyVal := strings.Split(str, ":")
+			if len(keyVal) > 1 && strings.ToLower(keyVal[0]) == "granularity" {
+				num, err := strconv.Atoi(keyVal[1])
+				if err != nil || num < 0 {
+					continue
+				}
+				return num
+			}
+		}
+	}
+	return m.Dialector.DefaultGranularity
+}
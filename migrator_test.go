@@ -0,0 +1,89 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// execRecorder is a minimal gorm.ConnPool fake that only needs to serve
+// ExecContext, since Migrator's DDL helpers go through db.Exec, which
+// (see gorm's callbacks/raw.go) calls ConnPool.ExecContext directly rather
+// than preparing a statement.
+type execRecorder struct {
+	queries []string
+}
+
+func (r *execRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (r *execRecorder) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	panic("PrepareContext not supported by execRecorder")
+}
+
+func (r *execRecorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	panic("QueryContext not supported by execRecorder")
+}
+
+func (r *execRecorder) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	panic("QueryRowContext not supported by execRecorder")
+}
+
+type distTestModel struct {
+	ID uint
+}
+
+func (distTestModel) DistributedTable() Distributed {
+	return Distributed{Cluster: "c1"}
+}
+
+func openRecordingDB(t *testing.T, recorder *execRecorder) *gorm.DB {
+	t.Helper()
+	dialector := New(Config{Conn: recorder, SkipInitializeWithVersion: true})
+	db, err := gorm.Open(dialector, &gorm.Config{
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	return db
+}
+
+func TestDropTableDropsLocalTableForDistributedTabler(t *testing.T) {
+	recorder := &execRecorder{}
+	db := openRecordingDB(t, recorder)
+
+	if err := db.Migrator().DropTable(&distTestModel{}); err != nil {
+		t.Fatalf("DropTable: %v", err)
+	}
+
+	if len(recorder.queries) != 2 {
+		t.Fatalf("got %d DROP TABLE statements, want 2 (wrapper + local): %v", len(recorder.queries), recorder.queries)
+	}
+	wrapperQuery, localQuery := recorder.queries[0], recorder.queries[1]
+	if got, want := wrapperQuery, "DROP TABLE IF EXISTS `dist_test_models`"; got != want {
+		t.Errorf("wrapper DROP TABLE = %q, want %q", got, want)
+	}
+	if got, want := localQuery, "DROP TABLE IF EXISTS `dist_test_models_local`"; got != want {
+		t.Errorf("local DROP TABLE = %q, want %q", got, want)
+	}
+}
+
+func TestDropTableOrdinaryModel(t *testing.T) {
+	recorder := &execRecorder{}
+	db := openRecordingDB(t, recorder)
+
+	if err := db.Migrator().DropTable(&batchTestRow{}); err != nil {
+		t.Fatalf("DropTable: %v", err)
+	}
+
+	if len(recorder.queries) != 1 {
+		t.Fatalf("got %d DROP TABLE statements, want 1: %v", len(recorder.queries), recorder.queries)
+	}
+}
@@ -0,0 +1,320 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// TableEngine renders the `ENGINE = ...` clause of a CREATE TABLE statement.
+// Implement it on a model via ClickHouseTabler to pick anything other than
+// the dialector's DefaultTableEngineOpts.
+type TableEngine interface {
+	Engine() string
+}
+
+// Setting is a single `SETTINGS key = value` pair. Settings are rendered in
+// the order given, since ClickHouse settings are not ordering-sensitive but
+// generated DDL should still be deterministic.
+type Setting struct {
+	Key   string
+	Value interface{}
+}
+
+// TableOptions holds the MergeTree-family clauses that sit between the
+// column list and the closing of a CREATE TABLE statement. Fields left at
+// their zero value are omitted from the generated DDL, except OrderBy which
+// falls back to "tuple()" when nothing else (struct tag or explicit option)
+// supplies one, matching the dialector's previous `ORDER BY tuple()` default.
+type TableOptions struct {
+	OrderBy     string
+	PartitionBy string
+	PrimaryKey  string
+	SampleBy    string
+	TTL         string
+	Settings    []Setting
+}
+
+// ClickHouseTabler is implemented by models that need an engine or table
+// options other than the dialector defaults, e.g.:
+//
+//	func (Event) ClickHouseTable() (clickhouse.TableEngine, clickhouse.TableOptions) {
+//		return clickhouse.ReplacingMergeTree{}, clickhouse.TableOptions{
+//			PartitionBy: "toYYYYMM(created_at)",
+//			OrderBy:     "(event_type, created_at)",
+//			TTL:         "created_at + INTERVAL 30 DAY",
+//		}
+//	}
+type ClickHouseTabler interface {
+	ClickHouseTable() (TableEngine, TableOptions)
+}
+
+func (o TableOptions) isZero() bool {
+	return o.OrderBy == "" && o.PartitionBy == "" && o.PrimaryKey == "" &&
+		o.SampleBy == "" && o.TTL == "" && len(o.Settings) == 0
+}
+
+// mergeTableOptions fills any zero field of base from fallback, letting an
+// explicit ClickHouseTabler option win over one parsed from struct tags.
+func mergeTableOptions(base, fallback TableOptions) TableOptions {
+	if base.OrderBy == "" {
+		base.OrderBy = fallback.OrderBy
+	}
+	if base.PartitionBy == "" {
+		base.PartitionBy = fallback.PartitionBy
+	}
+	if base.PrimaryKey == "" {
+		base.PrimaryKey = fallback.PrimaryKey
+	}
+	if base.SampleBy == "" {
+		base.SampleBy = fallback.SampleBy
+	}
+	if base.TTL == "" {
+		base.TTL = fallback.TTL
+	}
+	if len(base.Settings) == 0 {
+		base.Settings = fallback.Settings
+	}
+	return base
+}
+
+// clause renders "ENGINE=... PARTITION BY ... ORDER BY ... PRIMARY KEY ... SAMPLE BY ... TTL ... SETTINGS ...".
+func (o TableOptions) clause(engine TableEngine) string {
+	var b strings.Builder
+	b.WriteString("ENGINE=")
+	b.WriteString(engine.Engine())
+
+	if o.PartitionBy != "" {
+		fmt.Fprintf(&b, " PARTITION BY %s", o.PartitionBy)
+	}
+
+	orderBy := o.OrderBy
+	if orderBy == "" {
+		orderBy = "tuple()"
+	}
+	fmt.Fprintf(&b, " ORDER BY %s", orderBy)
+
+	if o.PrimaryKey != "" {
+		fmt.Fprintf(&b, " PRIMARY KEY %s", o.PrimaryKey)
+	}
+	if o.SampleBy != "" {
+		fmt.Fprintf(&b, " SAMPLE BY %s", o.SampleBy)
+	}
+	if o.TTL != "" {
+		fmt.Fprintf(&b, " TTL %s", o.TTL)
+	}
+	if len(o.Settings) > 0 {
+		parts := make([]string, len(o.Settings))
+		for i, s := range o.Settings {
+			parts[i] = fmt.Sprintf("%s = %v", s.Key, s.Value)
+		}
+		fmt.Fprintf(&b, " SETTINGS %s", strings.Join(parts, ", "))
+	}
+
+	return b.String()
+}
+
+// Built-in table engines. ReplacingMergeTree's Version and
+// SummingMergeTree's Columns are optional: left empty, the engine is
+// rendered without arguments and the ClickHouse-side default is used. The
+// other engines render their fields as given, empty or not.
+
+type MergeTree struct{}
+
+func (MergeTree) Engine() string { return "MergeTree()" }
+
+// ReplacingMergeTree de-duplicates rows sharing the same ORDER BY key,
+// keeping the one with the greatest Version (or the last inserted, if
+// Version is empty).
+type ReplacingMergeTree struct {
+	Version string
+}
+
+func (e ReplacingMergeTree) Engine() string {
+	if e.Version == "" {
+		return "ReplacingMergeTree()"
+	}
+	return fmt.Sprintf("ReplacingMergeTree(%s)", e.Version)
+}
+
+// SummingMergeTree sums the given numeric Columns on merge instead of
+// keeping the last value. Columns defaults to every non-key numeric column
+// when empty.
+type SummingMergeTree struct {
+	Columns []string
+}
+
+func (e SummingMergeTree) Engine() string {
+	if len(e.Columns) == 0 {
+		return "SummingMergeTree()"
+	}
+	return fmt.Sprintf("SummingMergeTree(%s)", strings.Join(e.Columns, ", "))
+}
+
+type AggregatingMergeTree struct{}
+
+func (AggregatingMergeTree) Engine() string { return "AggregatingMergeTree()" }
+
+// CollapsingMergeTree collapses row pairs that share an ORDER BY key and
+// whose Sign column carries opposite signs (+1 / -1).
+type CollapsingMergeTree struct {
+	Sign string
+}
+
+func (e CollapsingMergeTree) Engine() string {
+	return fmt.Sprintf("CollapsingMergeTree(%s)", e.Sign)
+}
+
+// VersionedCollapsingMergeTree is CollapsingMergeTree with an explicit
+// Version column to make collapsing order-independent.
+type VersionedCollapsingMergeTree struct {
+	Sign    string
+	Version string
+}
+
+func (e VersionedCollapsingMergeTree) Engine() string {
+	return fmt.Sprintf("VersionedCollapsingMergeTree(%s, %s)", e.Sign, e.Version)
+}
+
+// ReplicatedMergeTree is the replicated form of MergeTree, keyed by a
+// ZooKeeper path and replica name (typically using the {shard}/{replica}
+// macros). See also the Replicated() helper, which wraps any TableEngine
+// the same way.
+type ReplicatedMergeTree struct {
+	ZooPath string
+	Replica string
+}
+
+func (e ReplicatedMergeTree) Engine() string {
+	return fmt.Sprintf("ReplicatedMergeTree('%s', '%s')", e.ZooPath, e.Replica)
+}
+
+// Distributed fans reads/writes for ShardingKey out across Cluster to
+// Database.LocalTable on each shard.
+type Distributed struct {
+	Cluster     string
+	Database    string
+	LocalTable  string
+	ShardingKey string
+}
+
+func (e Distributed) Engine() string {
+	if e.ShardingKey == "" {
+		return fmt.Sprintf("Distributed(%s, %s, %s)", e.Cluster, e.Database, e.LocalTable)
+	}
+	return fmt.Sprintf("Distributed(%s, %s, %s, %s)", e.Cluster, e.Database, e.LocalTable, e.ShardingKey)
+}
+
+// replicatedEngine wraps another MergeTree-family engine into its Replicated*
+// equivalent, threading a ZooKeeper path and replica name in as its first two
+// constructor arguments. Build one with Replicated.
+type replicatedEngine struct {
+	zooPath string
+	replica string
+	engine  TableEngine
+}
+
+// Replicated wraps engine into its Replicated* equivalent, keyed by zooPath
+// and replica - conventionally left as the literal {shard}/{replica} macros,
+// which ClickHouse expands from its own config at table-creation time:
+//
+//	clickhouse.Replicated(clickhouse.ReplacingMergeTree{},
+//		"/clickhouse/tables/{shard}/events", "{replica}")
+//
+// renders `ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/events', '{replica}')`.
+func Replicated(engine TableEngine, zooPath, replica string) TableEngine {
+	return replicatedEngine{zooPath: zooPath, replica: replica, engine: engine}
+}
+
+func (e replicatedEngine) Engine() string {
+	name, args := splitEngine(e.engine.Engine())
+	zkArgs := fmt.Sprintf("'%s', '%s'", e.zooPath, e.replica)
+	if args != "" {
+		zkArgs += ", " + args
+	}
+	return fmt.Sprintf("Replicated%s(%s)", name, zkArgs)
+}
+
+// splitEngine splits an Engine() string such as "SummingMergeTree(a, b)"
+// into its name ("SummingMergeTree") and constructor arguments ("a, b").
+func splitEngine(s string) (name, args string) {
+	i := strings.IndexByte(s, '(')
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSuffix(s[i+1:], ")")
+}
+
+// DistributedTabler is implemented by models that should be migrated as a
+// pair of tables: a local MergeTree-family table, named "<table>_local" and
+// built the same way an ordinary model would be (engine/options from
+// ClickHouseTabler and struct tags, columns and indexes from the schema),
+// and a Distributed table under the model's own name fanning reads/writes
+// out across it. DistributedTable supplies the Distributed engine;
+// CreateTable fills in LocalTable and Database when left zero.
+type DistributedTabler interface {
+	DistributedTable() Distributed
+}
+
+// Kafka consumes a topic as a queue; real configuration (brokers, group,
+// format, ...) is supplied via TableOptions.Settings (kafka_broker_list,
+// kafka_topic_list, kafka_group_name, kafka_format, ...).
+type Kafka struct{}
+
+func (Kafka) Engine() string { return "Kafka" }
+
+type Log struct{}
+
+func (Log) Engine() string { return "Log" }
+
+type Memory struct{}
+
+func (Memory) Engine() string { return "Memory" }
+
+// tableOptionsFromTags collects PARTITION BY / ORDER BY / PRIMARY KEY /
+// SAMPLE BY / TTL fragments from field-level struct tags, e.g.
+// `gorm:"partition_by:toYYYYMM(created_at)"`. ORDER_BY and PRIMARY_KEY may be
+// used as bare markers (`gorm:"order_by"`), in which case the column itself
+// is added to the tuple in schema field order; any other value is used
+// verbatim as the full clause and wins over every marker, before or after it.
+func tableOptionsFromTags(dbNames []string, fieldsByDBName map[string]*schema.Field) TableOptions {
+	var opts TableOptions
+	var orderByCols, primaryKeyCols []string
+
+	for _, dbName := range dbNames {
+		field := fieldsByDBName[dbName]
+		if v, ok := field.TagSettings["PARTITION_BY"]; ok && opts.PartitionBy == "" {
+			opts.PartitionBy = v
+		}
+		if v, ok := field.TagSettings["SAMPLE_BY"]; ok && opts.SampleBy == "" {
+			opts.SampleBy = v
+		}
+		if v, ok := field.TagSettings["TTL"]; ok && opts.TTL == "" {
+			opts.TTL = v
+		}
+		if v, ok := field.TagSettings["ORDER_BY"]; ok {
+			if v == "ORDER_BY" {
+				orderByCols = append(orderByCols, dbName)
+			} else {
+				opts.OrderBy = v
+			}
+		}
+		if v, ok := field.TagSettings["PRIMARY_KEY"]; ok {
+			if v == "PRIMARY_KEY" {
+				primaryKeyCols = append(primaryKeyCols, dbName)
+			} else {
+				opts.PrimaryKey = v
+			}
+		}
+	}
+
+	if opts.OrderBy == "" && len(orderByCols) > 0 {
+		opts.OrderBy = "(" + strings.Join(orderByCols, ", ") + ")"
+	}
+	if opts.PrimaryKey == "" && len(primaryKeyCols) > 0 {
+		opts.PrimaryKey = "(" + strings.Join(primaryKeyCols, ", ") + ")"
+	}
+
+	return opts
+}
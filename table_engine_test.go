@@ -0,0 +1,146 @@
+package clickhouse
+
+import (
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+func TestSplitEngine(t *testing.T) {
+	tests := []struct {
+		in, wantName, wantArgs string
+	}{
+		{"MergeTree()", "MergeTree", ""},
+		{"SummingMergeTree(a, b)", "SummingMergeTree", "a, b"},
+		{"Kafka", "Kafka", ""},
+	}
+	for _, tt := range tests {
+		name, args := splitEngine(tt.in)
+		if name != tt.wantName || args != tt.wantArgs {
+			t.Errorf("splitEngine(%q) = (%q, %q), want (%q, %q)", tt.in, name, args, tt.wantName, tt.wantArgs)
+		}
+	}
+}
+
+func TestReplicated(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine TableEngine
+		want   string
+	}{
+		{
+			"no-arg engine",
+			MergeTree{},
+			"ReplicatedMergeTree('/clickhouse/tables/{shard}/t', '{replica}')",
+		},
+		{
+			"engine with constructor args",
+			ReplacingMergeTree{Version: "updated_at"},
+			"ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/t', '{replica}', updated_at)",
+		},
+	}
+	for _, tt := range tests {
+		got := Replicated(tt.engine, "/clickhouse/tables/{shard}/t", "{replica}").Engine()
+		if got != tt.want {
+			t.Errorf("%s: Engine() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTableEngineStrings(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine TableEngine
+		want   string
+	}{
+		{"MergeTree", MergeTree{}, "MergeTree()"},
+		{"ReplacingMergeTree no version", ReplacingMergeTree{}, "ReplacingMergeTree()"},
+		{"ReplacingMergeTree with version", ReplacingMergeTree{Version: "ver"}, "ReplacingMergeTree(ver)"},
+		{"SummingMergeTree no columns", SummingMergeTree{}, "SummingMergeTree()"},
+		{"SummingMergeTree with columns", SummingMergeTree{Columns: []string{"a", "b"}}, "SummingMergeTree(a, b)"},
+		{"AggregatingMergeTree", AggregatingMergeTree{}, "AggregatingMergeTree()"},
+		{"CollapsingMergeTree", CollapsingMergeTree{Sign: "sign"}, "CollapsingMergeTree(sign)"},
+		{"VersionedCollapsingMergeTree", VersionedCollapsingMergeTree{Sign: "sign", Version: "ver"}, "VersionedCollapsingMergeTree(sign, ver)"},
+		{"ReplicatedMergeTree", ReplicatedMergeTree{ZooPath: "/p", Replica: "{replica}"}, "ReplicatedMergeTree('/p', '{replica}')"},
+		{"Distributed without sharding key", Distributed{Cluster: "c", Database: "db", LocalTable: "t_local"}, "Distributed(c, db, t_local)"},
+		{"Distributed with sharding key", Distributed{Cluster: "c", Database: "db", LocalTable: "t_local", ShardingKey: "rand()"}, "Distributed(c, db, t_local, rand())"},
+		{"Kafka", Kafka{}, "Kafka"},
+		{"Log", Log{}, "Log"},
+		{"Memory", Memory{}, "Memory"},
+	}
+	for _, tt := range tests {
+		if got := tt.engine.Engine(); got != tt.want {
+			t.Errorf("%s: Engine() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTableOptionsClause(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TableOptions
+		want string
+	}{
+		{
+			"defaults to tuple() order by",
+			TableOptions{},
+			"ENGINE=MergeTree() ORDER BY tuple()",
+		},
+		{
+			"every clause",
+			TableOptions{
+				OrderBy:     "(event_date, id)",
+				PartitionBy: "toYYYYMM(event_date)",
+				PrimaryKey:  "(event_date)",
+				SampleBy:    "id",
+				TTL:         "event_date + INTERVAL 30 DAY",
+				Settings:    []Setting{{Key: "index_granularity", Value: 8192}},
+			},
+			"ENGINE=MergeTree() PARTITION BY toYYYYMM(event_date) ORDER BY (event_date, id) PRIMARY KEY (event_date) SAMPLE BY id TTL event_date + INTERVAL 30 DAY SETTINGS index_granularity = 8192",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.clause(MergeTree{}); got != tt.want {
+				t.Errorf("clause() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableOptionsFromTags(t *testing.T) {
+	type model struct {
+		EventDate string `gorm:"partition_by:toYYYYMM(event_date)"`
+		ID        uint   `gorm:"order_by;primary_key"`
+		EventType string `gorm:"order_by"`
+		Name      string `gorm:"sample_by:id;ttl:event_date + INTERVAL 30 DAY"`
+	}
+
+	s, err := schema.Parse(&model{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse: %v", err)
+	}
+
+	dbNames := make([]string, 0, len(s.FieldsByDBName))
+	for _, f := range s.Fields {
+		dbNames = append(dbNames, f.DBName)
+	}
+
+	opts := tableOptionsFromTags(dbNames, s.FieldsByDBName)
+	if opts.PartitionBy != "toYYYYMM(event_date)" {
+		t.Errorf("PartitionBy = %q", opts.PartitionBy)
+	}
+	if opts.OrderBy != "(id, event_type)" {
+		t.Errorf("OrderBy = %q, want (id, event_type)", opts.OrderBy)
+	}
+	if opts.PrimaryKey != "(id)" {
+		t.Errorf("PrimaryKey = %q, want (id)", opts.PrimaryKey)
+	}
+	if opts.SampleBy != "id" {
+		t.Errorf("SampleBy = %q", opts.SampleBy)
+	}
+	if opts.TTL != "event_date + INTERVAL 30 DAY" {
+		t.Errorf("TTL = %q", opts.TTL)
+	}
+}
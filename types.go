@@ -0,0 +1,213 @@
+package clickhouse
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// TypeMapper maps a schema field to the ClickHouse DDL type used to declare
+// it, e.g. []string -> "Array(String)" or *string -> "Nullable(String)". It
+// returns ok=false to decline, letting DataTypeOf fall back to its own switch
+// over field.DataType and then to the literal `gorm:"type:..."` tag (or an
+// empty string if none was given). It receives
+// the whole *schema.Field, not just its Go type, because some ClickHouse
+// types (Enum8/Enum16, Decimal(P,S), Tuple, Nested) can only be built from
+// struct tags GORM doesn't otherwise interpret.
+type TypeMapper func(field *schema.Field) (sqlType string, ok bool)
+
+// IPv4 is net.IP's counterpart for ClickHouse's IPv4 column type. net.IP
+// itself is always treated as IPv6 by DefaultTypeMapper, since Go has no way
+// to tell a 4-byte-intended net.IP from a 16-byte one by type alone; embed
+// IPv4 instead of net.IP on fields that hold dotted-quad addresses.
+type IPv4 net.IP
+
+// IPv6 is a named alias for net.IP kept for symmetry with IPv4 and for
+// models that want to be explicit about which ClickHouse column type a
+// field maps to, rather than relying on DefaultTypeMapper's net.IP match.
+type IPv6 net.IP
+
+// DefaultTypeMapper is a TypeMapper covering the ClickHouse shapes that come
+// up most often:
+//
+//   - Enum8/Enum16, from a `gorm:"enum8:a=1,b=2"` or `gorm:"enum16:..."` tag
+//   - Decimal(P,S), from `gorm:"precision:...;scale:..."` on any field other
+//     than a float or time.Time one (DataTypeOf already handles those itself)
+//   - Tuple(...)/Nested(...), from a literal `gorm:"tuple:..."` or
+//     `gorm:"nested:..."` tag giving the parenthesized contents verbatim
+//   - Array(T) for slices, Nullable(T) for pointers, Map(K, V) for maps
+//   - UUID for github.com/google/uuid.UUID, IPv4/IPv6 for this package's
+//     IPv4/IPv6 types, and IPv6 for net.IP (matched by name so this package
+//     doesn't have to depend on uuid directly)
+//
+// Assign it to Config.TypeMapper, or wrap it to add project-specific rules:
+//
+//	clickhouse.Config{TypeMapper: func(field *schema.Field) (string, bool) {
+//		if field.FieldType == reflect.TypeOf(MyEnum(0)) {
+//			return "Enum8('a' = 1, 'b' = 2)", true
+//		}
+//		return clickhouse.DefaultTypeMapper(field)
+//	}}
+//
+// A field only reaches DefaultTypeMapper at all if GORM's own schema.Parse
+// didn't already resolve it to something else first - notably, a bare
+// []string or map[string]V field is treated as a relation and fails to
+// parse entirely unless it carries an explicit `gorm:"type:..."` tag (GORM
+// itself has no opt-out for relation detection otherwise); once tagged, its
+// literal type is used as-is and DefaultTypeMapper's Array/Map branches
+// never run for it. Those branches remain reachable for custom TypeMapper
+// wrappers that build a synthetic *schema.Field.
+//
+// Values for Array/Nullable/Map columns round-trip through database/sql as
+// the slice/map/pointer types themselves when the driver is clickhouse-go/v2
+// (see https://github.com/ClickHouse/clickhouse-go's Scan/columnar support);
+// on the v1 driver, scan into the types documented under
+// github.com/ClickHouse/clickhouse-go/lib/column instead.
+func DefaultTypeMapper(field *schema.Field) (string, bool) {
+	if sqlType, ok := enumFromTag(field); ok {
+		return sqlType, true
+	}
+	if sqlType, ok := tupleOrNestedFromTag(field); ok {
+		return sqlType, true
+	}
+	// schema.Time already carries its own precision tag meaning (DateTime64
+	// sub-second digits, handled by DataTypeOf itself) - exclude it here so a
+	// `gorm:"precision:..."` tag on a time.Time field isn't misread as a
+	// request for a decimal column.
+	if field.Precision > 0 && field.DataType != schema.Float && field.DataType != schema.Time {
+		return fmt.Sprintf("decimal(%d, %d)", field.Precision, field.Scale), true
+	}
+
+	// Any scalar field GORM already resolved to a DataType (String/Bytes/
+	// Bool/Int/Uint/Float/Time) - whether its Go type is a plain builtin or a
+	// named one such as `type UserID int32` or `type Code string` - is
+	// already fully handled by DataTypeOf's own Size/Precision-aware switch;
+	// declining here keeps that logic authoritative instead of shadowing a
+	// `gorm:"size:..."` tag with the bare Kind-based rendering below.
+	t := field.FieldType
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		// A composite field with an explicit `gorm:"type:..."` tag (e.g.
+		// Array(LowCardinality(String)), or even a tag that happens to spell
+		// one of GORM's own DataType keywords, like "type:string" to flatten
+		// a slice into a single column) already got that exact string as its
+		// DataType, bypassing GORM's relation detection; respect it instead
+		// of overwriting it with the generic Array/Map rendering below.
+		// TagSettings still carries the raw "TYPE" tag value even after GORM
+		// uses it to set DataType, so check presence there rather than
+		// guessing from the resolved DataType. An empty/tagless DataType
+		// (the uuid.UUID/net.IP case, or an untagged composite a custom
+		// TypeMapper built synthetically) falls through as before.
+		if _, ok := field.TagSettings["TYPE"]; ok {
+			return "", false
+		}
+	default:
+		if field.DataType != "" {
+			return "", false
+		}
+	}
+
+	return mapReflectType(t)
+}
+
+func enumFromTag(field *schema.Field) (string, bool) {
+	if v, ok := field.TagSettings["ENUM8"]; ok {
+		return enumClause("Enum8", v), true
+	}
+	if v, ok := field.TagSettings["ENUM16"]; ok {
+		return enumClause("Enum16", v), true
+	}
+	return "", false
+}
+
+// enumClause renders a `key1=1,key2=2` tag value as ClickHouse's
+// Enum8('key1' = 1, 'key2' = 2) syntax.
+func enumClause(kind, tagValue string) string {
+	members := strings.Split(tagValue, ",")
+	parts := make([]string, 0, len(members))
+	for _, member := range members {
+		name, value, _ := strings.Cut(member, "=")
+		parts = append(parts, fmt.Sprintf("'%s' = %s", strings.TrimSpace(name), strings.TrimSpace(value)))
+	}
+	return fmt.Sprintf("%s(%s)", kind, strings.Join(parts, ", "))
+}
+
+func tupleOrNestedFromTag(field *schema.Field) (string, bool) {
+	if v, ok := field.TagSettings["TUPLE"]; ok {
+		return fmt.Sprintf("Tuple(%s)", v), true
+	}
+	if v, ok := field.TagSettings["NESTED"]; ok {
+		return fmt.Sprintf("Nested(%s)", v), true
+	}
+	return "", false
+}
+
+func mapReflectType(t reflect.Type) (string, bool) {
+	if t.Kind() == reflect.Ptr {
+		if sqlType, ok := mapReflectType(t.Elem()); ok {
+			return "Nullable(" + sqlType + ")", true
+		}
+		return "", false
+	}
+
+	if t.PkgPath() == "github.com/google/uuid" && t.Name() == "UUID" {
+		return "UUID", true
+	}
+	if t == reflect.TypeOf(IPv4{}) {
+		return "IPv4", true
+	}
+	if t == reflect.TypeOf(IPv6{}) {
+		return "IPv6", true
+	}
+	if t.PkgPath() == "net" && t.Name() == "IP" {
+		return "IPv6", true
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte already maps to schema.Bytes before TypeMapper is consulted.
+			return "", false
+		}
+		if sqlType, ok := mapReflectType(t.Elem()); ok {
+			return "Array(" + sqlType + ")", true
+		}
+		return "", false
+	case reflect.Map:
+		keyType, keyOk := mapReflectType(t.Key())
+		valType, valOk := mapReflectType(t.Elem())
+		if keyOk && valOk {
+			return "Map(" + keyType + ", " + valType + ")", true
+		}
+		return "", false
+	case reflect.String:
+		return "String", true
+	case reflect.Bool:
+		return "UInt8", true
+	case reflect.Int8:
+		return "Int8", true
+	case reflect.Int16:
+		return "Int16", true
+	case reflect.Int32:
+		return "Int32", true
+	case reflect.Int, reflect.Int64:
+		return "Int64", true
+	case reflect.Uint8:
+		return "UInt8", true
+	case reflect.Uint16:
+		return "UInt16", true
+	case reflect.Uint32:
+		return "UInt32", true
+	case reflect.Uint, reflect.Uint64:
+		return "UInt64", true
+	case reflect.Float32:
+		return "Float32", true
+	case reflect.Float64:
+		return "Float64", true
+	}
+
+	return "", false
+}
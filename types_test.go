@@ -0,0 +1,105 @@
+package clickhouse
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/schema"
+)
+
+func TestDefaultTypeMapper(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		wantType string
+		wantOk   bool
+	}{
+		{"string", "", "String", true},
+		{"bool", false, "UInt8", true},
+		{"int8", int8(0), "Int8", true},
+		{"int16", int16(0), "Int16", true},
+		{"int32", int32(0), "Int32", true},
+		{"int", int(0), "Int64", true},
+		{"int64", int64(0), "Int64", true},
+		{"uint64", uint64(0), "UInt64", true},
+		{"float32", float32(0), "Float32", true},
+		{"float64", float64(0), "Float64", true},
+		{"slice of string", []string{}, "Array(String)", true},
+		{"pointer to string", new(string), "Nullable(String)", true},
+		{"map of string to int", map[string]int{}, "Map(String, Int64)", true},
+		{"uuid.UUID", uuid.UUID{}, "UUID", true},
+		{"net.IP", net.IP{}, "IPv6", true},
+		{"clickhouse.IPv4", IPv4{}, "IPv4", true},
+		{"clickhouse.IPv6", IPv6{}, "IPv6", true},
+		{"byte slice declines", []byte{}, "", false},
+		{"unmappable struct", struct{ X complex128 }{}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := &schema.Field{FieldType: reflect.TypeOf(tt.value)}
+			got, ok := DefaultTypeMapper(field)
+			if got != tt.wantType || ok != tt.wantOk {
+				t.Errorf("DefaultTypeMapper(%T) = (%q, %v), want (%q, %v)", tt.value, got, ok, tt.wantType, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestDefaultTypeMapperPointerToUnmappable(t *testing.T) {
+	type unmappable struct{ X complex128 }
+	field := &schema.Field{FieldType: reflect.TypeOf(new(unmappable))}
+	got, ok := DefaultTypeMapper(field)
+	if ok {
+		t.Errorf("DefaultTypeMapper(*unmappable) = (%q, true), want ok=false", got)
+	}
+}
+
+func TestDefaultTypeMapperEnumFromTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want string
+	}{
+		{"enum8", map[string]string{"ENUM8": "a=1,b=2"}, "Enum8('a' = 1, 'b' = 2)"},
+		{"enum16", map[string]string{"ENUM16": "a=1,b=2,c=3"}, "Enum16('a' = 1, 'b' = 2, 'c' = 3)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := &schema.Field{FieldType: reflect.TypeOf(""), TagSettings: tt.tags}
+			got, ok := DefaultTypeMapper(field)
+			if !ok || got != tt.want {
+				t.Errorf("DefaultTypeMapper() = (%q, %v), want (%q, true)", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultTypeMapperTupleAndNestedFromTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want string
+	}{
+		{"tuple", map[string]string{"TUPLE": "Int32, String"}, "Tuple(Int32, String)"},
+		{"nested", map[string]string{"NESTED": "name String, value Int32"}, "Nested(name String, value Int32)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := &schema.Field{FieldType: reflect.TypeOf(""), TagSettings: tt.tags}
+			got, ok := DefaultTypeMapper(field)
+			if !ok || got != tt.want {
+				t.Errorf("DefaultTypeMapper() = (%q, %v), want (%q, true)", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultTypeMapperDecimalFromPrecisionTag(t *testing.T) {
+	field := &schema.Field{FieldType: reflect.TypeOf(""), Precision: 10, Scale: 4}
+	got, ok := DefaultTypeMapper(field)
+	if want := "decimal(10, 4)"; !ok || got != want {
+		t.Errorf("DefaultTypeMapper() = (%q, %v), want (%q, true)", got, ok, want)
+	}
+}